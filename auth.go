@@ -0,0 +1,154 @@
+package ming
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNotYetValid = errors.New("token not yet valid")
+)
+
+func BearerToken(ctx *fasthttp.RequestCtx) (string, bool) {
+	auth := string(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization))
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+	if token == "" || strings.ContainsAny(token, " \t") {
+		return "", false
+	}
+	return token, true
+}
+
+func JWTAuth(validate func(token string) (claims interface{}, err error)) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			token, ok := BearerToken(ctx)
+			if !ok {
+				unauthorized(ctx)
+				return
+			}
+			claims, err := validate(token)
+			if err != nil {
+				unauthorized(ctx)
+				return
+			}
+			SetUser(ctx, claims)
+			next(ctx)
+		}
+	}
+}
+
+func unauthorized(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.Set(fasthttp.HeaderWWWAuthenticate, "Bearer")
+	writeError(ctx, "unauthorized", fasthttp.StatusUnauthorized)
+}
+
+type JWTKeys struct {
+	HMACSecret   []byte
+	RSAPublicKey *rsa.PublicKey
+	Leeway       time.Duration
+}
+
+func JWTVerify(keys JWTKeys) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return JWTAuth(func(token string) (interface{}, error) {
+		return ParseJWT(token, keys)
+	})
+}
+
+func ParseJWT(token string, keys JWTKeys) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := verifySignature(header.Alg, parts[0]+"."+parts[1], signature, keys); err != nil {
+		return nil, err
+	}
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(keys.Leeway)) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0).Add(-keys.Leeway)) {
+		return nil, ErrTokenNotYetValid
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func verifySignature(alg, signed string, signature []byte, keys JWTKeys) error {
+	var (
+		newHash    func() hash.Hash
+		cryptoHash crypto.Hash
+	)
+	if len(alg) != 5 {
+		return ErrInvalidToken
+	}
+	switch alg[2:] {
+	case "256":
+		newHash, cryptoHash = sha256.New, crypto.SHA256
+	case "384":
+		newHash, cryptoHash = sha512.New384, crypto.SHA384
+	case "512":
+		newHash, cryptoHash = sha512.New, crypto.SHA512
+	default:
+		return ErrInvalidToken
+	}
+	switch alg[:2] {
+	case "HS":
+		if len(keys.HMACSecret) == 0 {
+			return ErrInvalidToken
+		}
+		mac := hmac.New(newHash, keys.HMACSecret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidToken
+		}
+	case "RS":
+		if keys.RSAPublicKey == nil {
+			return ErrInvalidToken
+		}
+		h := newHash()
+		h.Write([]byte(signed))
+		if rsa.VerifyPKCS1v15(keys.RSAPublicKey, cryptoHash, h.Sum(nil), signature) != nil {
+			return ErrInvalidToken
+		}
+	default:
+		return ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package ming
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func signJWT(alg, claims string, sign func(signed []byte) []byte) string {
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString([]byte(`{"alg":"`+alg+`","typ":"JWT"}`)) + "." + enc.EncodeToString([]byte(claims))
+	return signed + "." + enc.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(secret []byte) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+}
+
+func rs256(key *rsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		sum := sha256.Sum256(signed)
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		return sig
+	}
+}
+
+func TestParseJWT(t *testing.T) {
+	secret := []byte("secret")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	hmacKeys := JWTKeys{HMACSecret: secret}
+	rsaKeys := JWTKeys{RSAPublicKey: &key.PublicKey}
+	tampered := signJWT("HS256", `{"sub":"alice"}`, hs256(secret))
+	tampered = tampered[:len(tampered)-2] + "AA"
+
+	cases := []struct {
+		name  string
+		token string
+		keys  JWTKeys
+		err   error
+	}{
+		{"hs256 valid", signJWT("HS256", fmt.Sprintf(`{"sub":"alice","exp":%d}`, now+60), hs256(secret)), hmacKeys, nil},
+		{"rs256 valid", signJWT("RS256", fmt.Sprintf(`{"sub":"alice","nbf":%d}`, now-60), rs256(key)), rsaKeys, nil},
+		{"expired", signJWT("HS256", fmt.Sprintf(`{"exp":%d}`, now-60), hs256(secret)), hmacKeys, ErrTokenExpired},
+		{"expired within leeway", signJWT("HS256", fmt.Sprintf(`{"exp":%d}`, now-60), hs256(secret)), JWTKeys{HMACSecret: secret, Leeway: 2 * time.Minute}, nil},
+		{"not yet valid", signJWT("HS256", fmt.Sprintf(`{"nbf":%d}`, now+60), hs256(secret)), hmacKeys, ErrTokenNotYetValid},
+		{"nbf within leeway", signJWT("HS256", fmt.Sprintf(`{"nbf":%d}`, now+60), hs256(secret)), JWTKeys{HMACSecret: secret, Leeway: 2 * time.Minute}, nil},
+		{"tampered signature", tampered, hmacKeys, ErrInvalidToken},
+		{"wrong secret", signJWT("HS256", `{}`, hs256([]byte("other"))), hmacKeys, ErrInvalidToken},
+		{"alg none", signJWT("none", `{}`, func([]byte) []byte { return nil }), hmacKeys, ErrInvalidToken},
+		{"hs token with rsa key only", signJWT("HS256", `{}`, hs256(secret)), rsaKeys, ErrInvalidToken},
+		{"malformed", "not.a-token", hmacKeys, ErrInvalidToken},
+	}
+	for _, c := range cases {
+		claims, err := ParseJWT(c.token, c.keys)
+		if err != c.err {
+			t.Errorf("%s: err = %v, want %v", c.name, err, c.err)
+			continue
+		}
+		if err == nil && claims == nil {
+			t.Errorf("%s: claims are nil", c.name)
+		}
+	}
+}
+
+func TestJWTVerifyStoresClaims(t *testing.T) {
+	secret := []byte("secret")
+	r := New()
+	r.Get("/", JWTVerify(JWTKeys{HMACSecret: secret})(func(ctx *fasthttp.RequestCtx) {
+		claims, _ := User[map[string]interface{}](ctx)
+		fmt.Fprint(ctx, claims["sub"])
+	}))
+	get := func(token string) *fasthttp.Response {
+		ctx := newCtx(fasthttp.MethodGet, "/")
+		ctx.Request.Header.Set(fasthttp.HeaderAuthorization, "Bearer "+token)
+		r.Handler(ctx)
+		return &ctx.Response
+	}
+
+	resp := get(signJWT("HS256", `{"sub":"alice"}`, hs256(secret)))
+	if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "alice" {
+		t.Fatalf("valid token: %d %q", resp.StatusCode(), resp.Body())
+	}
+	expired := signJWT("HS256", fmt.Sprintf(`{"exp":%d}`, time.Now().Unix()-60), hs256(secret))
+	if resp := get(expired); resp.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("expired token status = %d, want 401", resp.StatusCode())
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	cases := []struct {
+		header string
+		token  string
+		ok     bool
+	}{
+		{"Bearer abc.def", "abc.def", true},
+		{"bearer abc", "abc", true},
+		{"BEARER  abc ", "abc", true},
+		{"", "", false},
+		{"Basic dXNlcjpwYXNz", "", false},
+		{"Bearer ", "", false},
+		{"Bearer a b", "", false},
+	}
+	for _, c := range cases {
+		ctx := newCtx(fasthttp.MethodGet, "/")
+		if c.header != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAuthorization, c.header)
+		}
+		token, ok := BearerToken(ctx)
+		if token != c.token || ok != c.ok {
+			t.Errorf("BearerToken(%q) = %q, %v; want %q, %v", c.header, token, ok, c.token, c.ok)
+		}
+	}
+}
+
+func TestJWTAuth(t *testing.T) {
+	r := New()
+	r.Get("/", JWTAuth(func(token string) (interface{}, error) {
+		if token != "good" {
+			return nil, ErrInvalidToken
+		}
+		return "alice", nil
+	})(func(ctx *fasthttp.RequestCtx) {
+		user, _ := User[string](ctx)
+		ctx.WriteString(user)
+	}))
+	get := func(header string) *fasthttp.Response {
+		ctx := newCtx(fasthttp.MethodGet, "/")
+		if header != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAuthorization, header)
+		}
+		r.Handler(ctx)
+		return &ctx.Response
+	}
+
+	if resp := get("Bearer good"); resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "alice" {
+		t.Fatalf("valid token: %d %q", resp.StatusCode(), resp.Body())
+	}
+	for _, header := range []string{"", "Bearer", "Token good", "Bearer bad"} {
+		resp := get(header)
+		if resp.StatusCode() != fasthttp.StatusUnauthorized {
+			t.Fatalf("%q status = %d, want 401", header, resp.StatusCode())
+		}
+		if got := string(resp.Header.Peek(fasthttp.HeaderWWWAuthenticate)); got != "Bearer" {
+			t.Fatalf("%q WWW-Authenticate = %q, want Bearer", header, got)
+		}
+	}
+}
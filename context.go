@@ -0,0 +1,100 @@
+package ming
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	userKey    = "ming.user"
+	contextKey = "ming.context"
+	pageKey    = "ming.page"
+	noMatchKey = "ming.nomatch"
+	errorKey   = "ming.error"
+)
+
+func SetUser[T any](ctx *fasthttp.RequestCtx, user T) {
+	ctx.SetUserValue(userKey, user)
+}
+
+func User[T any](ctx *fasthttp.RequestCtx) (T, bool) {
+	user, ok := ctx.UserValue(userKey).(T)
+	return user, ok
+}
+
+func WithDeadline(d time.Duration) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			c, cancel := context.WithTimeout(RequestContext(ctx), d)
+			defer cancel()
+			ctx.SetUserValue(contextKey, c)
+			next(ctx)
+		}
+	}
+}
+
+func RequestContext(ctx *fasthttp.RequestCtx) context.Context {
+	if c, ok := ctx.UserValue(contextKey).(context.Context); ok {
+		return c
+	}
+	return ctx
+}
+
+type PageInfo struct {
+	Page   int
+	Limit  int
+	Offset int
+}
+
+func Pagination(defaultLimit, maxLimit int) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			page, ok := queryInt(ctx, "page", 1)
+			if !ok || page < 1 {
+				writeError(ctx, "invalid page", fasthttp.StatusBadRequest)
+				return
+			}
+			limit, ok := queryInt(ctx, "limit", defaultLimit)
+			if !ok || limit < 1 {
+				writeError(ctx, "invalid limit", fasthttp.StatusBadRequest)
+				return
+			}
+			if maxLimit > 0 && limit > maxLimit {
+				limit = maxLimit
+			}
+			if page-1 > math.MaxInt/limit {
+				writeError(ctx, "invalid page", fasthttp.StatusBadRequest)
+				return
+			}
+			ctx.SetUserValue(pageKey, PageInfo{
+				Page:   page,
+				Limit:  limit,
+				Offset: (page - 1) * limit,
+			})
+			next(ctx)
+		}
+	}
+}
+
+func GetPagination(ctx *fasthttp.RequestCtx) PageInfo {
+	info, _ := ctx.UserValue(pageKey).(PageInfo)
+	return info
+}
+
+func queryInt(ctx *fasthttp.RequestCtx, key string, def int) (int, bool) {
+	value := Query(ctx, key)
+	if len(value) == 0 {
+		return def, true
+	}
+	n, err := strconv.Atoi(string(value))
+	return n, err == nil
+}
+
+func NoMatchReason(ctx *fasthttp.RequestCtx) string {
+	reason, _ := ctx.UserValue(noMatchKey).(string)
+	return reason
+}
@@ -0,0 +1,79 @@
+package ming
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestPagination(t *testing.T) {
+	r := New()
+	r.Get("/", Pagination(20, 100)(func(ctx *fasthttp.RequestCtx) {
+		info := GetPagination(ctx)
+		fmt.Fprintf(ctx, "%d %d %d", info.Page, info.Limit, info.Offset)
+	}))
+	cases := []struct {
+		uri    string
+		status int
+		body   string
+	}{
+		{"/", fasthttp.StatusOK, "1 20 0"},
+		{"/?page=3&limit=10", fasthttp.StatusOK, "3 10 20"},
+		{"/?page=2&limit=500", fasthttp.StatusOK, "2 100 100"},
+		{"/?page=0", fasthttp.StatusBadRequest, ""},
+		{"/?page=abc", fasthttp.StatusBadRequest, ""},
+		{"/?limit=-1", fasthttp.StatusBadRequest, ""},
+		{"/?page=9223372036854775807", fasthttp.StatusBadRequest, ""},
+		{"/?page=99999999999999999999", fasthttp.StatusBadRequest, ""},
+	}
+	for _, c := range cases {
+		resp := r.ServeTest(fasthttp.MethodGet, c.uri, nil)
+		if resp.StatusCode() != c.status {
+			t.Errorf("%s status = %d, want %d", c.uri, resp.StatusCode(), c.status)
+			continue
+		}
+		if c.body != "" && string(resp.Body()) != c.body {
+			t.Errorf("%s body = %q, want %q", c.uri, resp.Body(), c.body)
+		}
+	}
+}
+
+func TestSetUser(t *testing.T) {
+	type account struct {
+		ID   int
+		Name string
+	}
+	ctx := newCtx(fasthttp.MethodGet, "/")
+	if _, ok := User[account](ctx); ok {
+		t.Fatal("User reported a value before SetUser")
+	}
+	SetUser(ctx, account{ID: 7, Name: "alice"})
+	user, ok := User[account](ctx)
+	if !ok || user.ID != 7 || user.Name != "alice" {
+		t.Fatalf("User = %+v, %v", user, ok)
+	}
+	if _, ok := User[string](ctx); ok {
+		t.Fatal("User returned a value for the wrong type")
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	r := New()
+	r.Get("/", WithDeadline(time.Minute)(func(ctx *fasthttp.RequestCtx) {
+		deadline, ok := RequestContext(ctx).Deadline()
+		if !ok {
+			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(ctx, time.Until(deadline) > 0 && time.Until(deadline) <= time.Minute)
+	}))
+	resp := r.ServeTest(fasthttp.MethodGet, "/", nil)
+	if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "true" {
+		t.Fatalf("deadline: %d %q", resp.StatusCode(), resp.Body())
+	}
+	if _, ok := RequestContext(newCtx(fasthttp.MethodGet, "/")).Deadline(); ok {
+		t.Fatal("context without WithDeadline has a deadline")
+	}
+}
@@ -0,0 +1,79 @@
+package ming
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"github.com/valyala/fasthttp"
+)
+
+const csrfKey = "ming.csrf"
+
+type CSRFOptions struct {
+	CookieName string
+	HeaderName string
+	FormField  string
+	CookiePath string
+	Secure     bool
+	MaxAge     int
+}
+
+func CSRF(opts CSRFOptions) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if opts.CookieName == "" {
+		opts.CookieName = "_csrf"
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = "X-CSRF-Token"
+	}
+	if opts.FormField == "" {
+		opts.FormField = "csrf_token"
+	}
+	if opts.CookiePath == "" {
+		opts.CookiePath = "/"
+	}
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			token := string(ctx.Request.Header.Cookie(opts.CookieName))
+			switch requestMethod(ctx) {
+			case fasthttp.MethodPost, fasthttp.MethodPut, fasthttp.MethodPatch, fasthttp.MethodDelete:
+				sent := ctx.Request.Header.Peek(opts.HeaderName)
+				if len(sent) == 0 {
+					sent = ctx.FormValue(opts.FormField)
+				}
+				if token == "" || subtle.ConstantTimeCompare([]byte(token), sent) != 1 {
+					writeError(ctx, "invalid csrf token", fasthttp.StatusForbidden)
+					return
+				}
+			default:
+				if token == "" {
+					token = newCSRFToken()
+					cookie := fasthttp.AcquireCookie()
+					cookie.SetKey(opts.CookieName)
+					cookie.SetValue(token)
+					cookie.SetPath(opts.CookiePath)
+					cookie.SetSecure(opts.Secure)
+					cookie.SetMaxAge(opts.MaxAge)
+					cookie.SetSameSite(fasthttp.CookieSameSiteStrictMode)
+					ctx.Response.Header.SetCookie(cookie)
+					fasthttp.ReleaseCookie(cookie)
+				}
+			}
+			ctx.SetUserValue(csrfKey, token)
+			next(ctx)
+		}
+	}
+}
+
+func CSRFToken(ctx *fasthttp.RequestCtx) string {
+	token, _ := ctx.UserValue(csrfKey).(string)
+	return token
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
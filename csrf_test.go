@@ -0,0 +1,60 @@
+package ming
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestCSRF(t *testing.T) {
+	r := New()
+	r.Use(CSRF(CSRFOptions{}))
+	r.Get("/form", func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString(CSRFToken(ctx))
+	})
+	r.Post("/form", ok)
+
+	ctx := newCtx(fasthttp.MethodGet, "/form")
+	r.Handler(ctx)
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey("_csrf")
+	if !ctx.Response.Header.Cookie(cookie) {
+		t.Fatal("GET did not issue a csrf cookie")
+	}
+	token := string(cookie.Value())
+	if token == "" || string(ctx.Response.Body()) != token {
+		t.Fatalf("issued token %q, handler saw %q", token, ctx.Response.Body())
+	}
+
+	post := func(cookieToken, header, form string) int {
+		ctx := newCtx(fasthttp.MethodPost, "/form")
+		if cookieToken != "" {
+			ctx.Request.Header.SetCookie("_csrf", cookieToken)
+		}
+		if header != "" {
+			ctx.Request.Header.Set("X-CSRF-Token", header)
+		}
+		if form != "" {
+			ctx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+			ctx.Request.SetBodyString("csrf_token=" + form)
+		}
+		r.Handler(ctx)
+		return ctx.Response.StatusCode()
+	}
+	if status := post(token, token, ""); status != fasthttp.StatusOK {
+		t.Fatalf("matching header status = %d, want 200", status)
+	}
+	if status := post(token, "", token); status != fasthttp.StatusOK {
+		t.Fatalf("matching form field status = %d, want 200", status)
+	}
+	if status := post(token, "wrong", ""); status != fasthttp.StatusForbidden {
+		t.Fatalf("mismatched token status = %d, want 403", status)
+	}
+	if status := post("", token, ""); status != fasthttp.StatusForbidden {
+		t.Fatalf("missing cookie status = %d, want 403", status)
+	}
+	if status := post(token, "", ""); status != fasthttp.StatusForbidden {
+		t.Fatalf("missing token status = %d, want 403", status)
+	}
+}
@@ -2,11 +2,12 @@ module github.com/hypnguyen1209/ming
 
 go 1.18
 
+require github.com/valyala/fasthttp v1.43.0
+
 require (
 	github.com/andybalholm/brotli v1.0.4 // indirect
 	github.com/fasthttp/router v1.4.14 // indirect
 	github.com/klauspost/compress v1.15.12 // indirect
 	github.com/savsgio/gotils v0.0.0-20220530130905-52f3993e8d6d // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
-	github.com/valyala/fasthttp v1.43.0 // indirect
 )
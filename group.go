@@ -0,0 +1,148 @@
+package ming
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type RouteGroup struct {
+	ErrorHandler func(*fasthttp.RequestCtx, error)
+	router       *Router
+	parent       *RouteGroup
+	prefix       string
+	middlewares  []func(fasthttp.RequestHandler) fasthttp.RequestHandler
+}
+
+func (r *Router) Group(prefix string) *RouteGroup {
+	return &RouteGroup{
+		router: r,
+		prefix: strings.TrimSuffix(prefix, "/"),
+	}
+}
+
+func (r *Router) Version(v string) *RouteGroup {
+	base := r.VersionBase
+	if base == "" {
+		base = "/api"
+	}
+	return r.Group(strings.TrimSuffix(base, "/") + "/" + v)
+}
+
+func (g *RouteGroup) Group(prefix string) *RouteGroup {
+	group := g.router.Group(g.prefix + prefix)
+	group.parent = g
+	return group
+}
+
+func (g *RouteGroup) handleError(ctx *fasthttp.RequestCtx, err error) {
+	for group := g; group != nil; group = group.parent {
+		if group.ErrorHandler != nil {
+			group.ErrorHandler(ctx, err)
+			return
+		}
+	}
+	g.router.handleError(ctx, err)
+}
+
+func (g *RouteGroup) Use(middlewares ...func(fasthttp.RequestHandler) fasthttp.RequestHandler) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+func (g *RouteGroup) UseFor(methods []string, middlewares ...func(fasthttp.RequestHandler) fasthttp.RequestHandler) {
+	for _, middleware := range middlewares {
+		g.Use(forMethods(methods, middleware))
+	}
+}
+
+func (g *RouteGroup) wrap(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	for group := g; group != nil; group = group.parent {
+		for i := len(group.middlewares) - 1; i >= 0; i-- {
+			handler = group.middlewares[i](handler)
+		}
+	}
+	return handler
+}
+
+func (g *RouteGroup) Handle(method, path string, handler fasthttp.RequestHandler) {
+	g.router.Handle(method, g.prefix+path, g.wrap(handler))
+}
+
+func (g *RouteGroup) Get(path string, handler fasthttp.RequestHandler) {
+	g.Handle(fasthttp.MethodGet, path, handler)
+}
+
+func (g *RouteGroup) Head(path string, handler fasthttp.RequestHandler) {
+	g.Handle(fasthttp.MethodHead, path, handler)
+}
+
+func (g *RouteGroup) Post(path string, handler fasthttp.RequestHandler) {
+	g.Handle(fasthttp.MethodPost, path, handler)
+}
+
+func (g *RouteGroup) Put(path string, handler fasthttp.RequestHandler) {
+	g.Handle(fasthttp.MethodPut, path, handler)
+}
+
+func (g *RouteGroup) Patch(path string, handler fasthttp.RequestHandler) {
+	g.Handle(fasthttp.MethodPatch, path, handler)
+}
+
+func (g *RouteGroup) Delete(path string, handler fasthttp.RequestHandler) {
+	g.Handle(fasthttp.MethodDelete, path, handler)
+}
+
+func (g *RouteGroup) Options(path string, handler fasthttp.RequestHandler) {
+	g.Handle(fasthttp.MethodOptions, path, handler)
+}
+
+func (g *RouteGroup) All(path string, handler fasthttp.RequestHandler) {
+	g.Handle("ALL", path, handler)
+}
+
+func (g *RouteGroup) HandleE(method, path string, handler HandlerE) {
+	g.router.addNode(&Node{
+		method:   method,
+		path:     g.prefix + path,
+		handler:  g.wrap(wrapE(handler, g.handleError)),
+		handlerE: handler,
+		group:    g,
+	})
+}
+
+func (g *RouteGroup) rebind(r *Router, groups map[*RouteGroup]*RouteGroup) *RouteGroup {
+	if clone, ok := groups[g]; ok {
+		return clone
+	}
+	clone := *g
+	clone.router = r
+	if g.parent != nil {
+		clone.parent = g.parent.rebind(r, groups)
+	}
+	groups[g] = &clone
+	return &clone
+}
+
+func (g *RouteGroup) GetE(path string, handler HandlerE) {
+	g.HandleE(fasthttp.MethodGet, path, handler)
+}
+
+func (g *RouteGroup) PostE(path string, handler HandlerE) {
+	g.HandleE(fasthttp.MethodPost, path, handler)
+}
+
+func (g *RouteGroup) PutE(path string, handler HandlerE) {
+	g.HandleE(fasthttp.MethodPut, path, handler)
+}
+
+func (g *RouteGroup) PatchE(path string, handler HandlerE) {
+	g.HandleE(fasthttp.MethodPatch, path, handler)
+}
+
+func (g *RouteGroup) DeleteE(path string, handler HandlerE) {
+	g.HandleE(fasthttp.MethodDelete, path, handler)
+}
+
+func (g *RouteGroup) AllE(path string, handler HandlerE) {
+	g.HandleE("ALL", path, handler)
+}
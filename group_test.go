@@ -0,0 +1,107 @@
+package ming
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func header(name, value string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Add(name, value)
+			next(ctx)
+		}
+	}
+}
+
+func TestGroupUseFor(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.UseFor([]string{fasthttp.MethodPost}, header("X-Write", "1"))
+	api.Get("/items", ok)
+	api.Post("/items", ok)
+	api.PostE("/errors", func(ctx *fasthttp.RequestCtx) error { return nil })
+	r.Post("/items", ok)
+
+	if resp := r.ServeTest(fasthttp.MethodPost, "/api/items", nil); string(resp.Header.Peek("X-Write")) != "1" {
+		t.Fatal("group middleware did not run for POST")
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/api/items", nil); len(resp.Header.Peek("X-Write")) != 0 {
+		t.Fatal("group middleware ran for GET")
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/api/errors", nil); string(resp.Header.Peek("X-Write")) != "1" {
+		t.Fatal("group middleware did not run for PostE")
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/items", nil); len(resp.Header.Peek("X-Write")) != 0 {
+		t.Fatal("group middleware ran outside the group")
+	}
+}
+
+func TestNestedGroupUse(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.Use(header("X-Order", "api"))
+	v1 := api.Group("/v1")
+	v1.Use(header("X-Order", "v1"))
+	v1.Get("/items", ok)
+
+	resp := r.ServeTest(fasthttp.MethodGet, "/api/v1/items", nil)
+	var order []string
+	resp.Header.VisitAll(func(key, value []byte) {
+		if string(key) == "X-Order" {
+			order = append(order, string(value))
+		}
+	})
+	if len(order) != 2 || order[0] != "api" || order[1] != "v1" {
+		t.Fatalf("middleware order = %v, want [api v1]", order)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	r := New()
+	r.Version("v1").Get("/users", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("v1") })
+	r.Version("v2").Get("/users", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("v2") })
+	r.Version("v2").Get("/teams", ok)
+
+	for uri, want := range map[string]string{"/api/v1/users": "v1", "/api/v2/users": "v2"} {
+		if resp := r.ServeTest(fasthttp.MethodGet, uri, nil); string(resp.Body()) != want {
+			t.Fatalf("%s body = %q, want %q", uri, resp.Body(), want)
+		}
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/api/v1/teams", nil); resp.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("v2-only route under v1 status = %d, want 404", resp.StatusCode())
+	}
+
+	r = New()
+	r.VersionBase = "/service/"
+	r.Version("v3").Get("/users", ok)
+	if resp := r.ServeTest(fasthttp.MethodGet, "/service/v3/users", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("custom base status = %d, want 200", resp.StatusCode())
+	}
+}
+
+func TestGroupErrorHandler(t *testing.T) {
+	fail := func(ctx *fasthttp.RequestCtx) error { return errors.New("boom") }
+	r := New()
+	api := r.Group("/api")
+	api.ErrorHandler = func(ctx *fasthttp.RequestCtx, err error) {
+		ctx.SetStatusCode(fasthttp.StatusBadGateway)
+		ctx.SetBodyString("api: " + err.Error())
+	}
+	api.GetE("/fail", fail)
+	api.Group("/v1").GetE("/fail", fail)
+	r.GetE("/fail", fail)
+
+	for _, uri := range []string{"/api/fail", "/api/v1/fail"} {
+		resp := r.ServeTest(fasthttp.MethodGet, uri, nil)
+		if resp.StatusCode() != fasthttp.StatusBadGateway || string(resp.Body()) != "api: boom" {
+			t.Fatalf("%s: %d %q, want group handler", uri, resp.StatusCode(), resp.Body())
+		}
+	}
+	resp := r.ServeTest(fasthttp.MethodGet, "/fail", nil)
+	if resp.StatusCode() != fasthttp.StatusInternalServerError || string(resp.Body()) != `{"error":"internal server error"}` {
+		t.Fatalf("non-group route: %d %q, want default handler", resp.StatusCode(), resp.Body())
+	}
+}
@@ -1,54 +1,374 @@
 package ming
 
 import (
+	"encoding/json"
 	"fmt"
+	"html"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/valyala/fasthttp"
 )
 
 func (r *Router) Handle(method, path string, handler fasthttp.RequestHandler) {
-	if !strings.HasPrefix(path, "/") {
-		panic("path must begin with \"/\" in \"" + path + "\"")
+	r.addNode(&Node{
+		method:  method,
+		path:    path,
+		handler: handler,
+	})
+}
+
+func (r *Router) addNode(n *Node) {
+	if !strings.HasPrefix(n.path, "/") {
+		panic("path must begin with \"/\" in \"" + n.path + "\"")
 	}
-	r.trees.Add(&Node{
+	n.path = unescapePath(n.path)
+	r.mu.Lock()
+	r.trees.Add(n)
+	r.resetMatchCache()
+	r.mu.Unlock()
+}
+
+func unescapePath(path string) string {
+	if strings.Contains(path, "%") {
+		if p, err := url.PathUnescape(path); err == nil {
+			return p
+		}
+	}
+	return path
+}
+
+func (r *Router) HandleQuery(method, path string, query map[string]string, handler fasthttp.RequestHandler) {
+	r.addNode(&Node{
 		method:  method,
 		path:    path,
 		handler: handler,
+		query:   query,
 	})
 }
 
+func (r *Router) GetQuery(path string, query map[string]string, handler fasthttp.RequestHandler) {
+	r.HandleQuery(fasthttp.MethodGet, path, query, handler)
+}
+
+func (r *Router) HandleBothSlash(method, path string, handler fasthttp.RequestHandler) {
+	r.Handle(method, path, handler)
+	if path == "/" {
+		return
+	}
+	if strings.HasSuffix(path, "/") {
+		r.Handle(method, strings.TrimSuffix(path, "/"), handler)
+	} else {
+		r.Handle(method, path+"/", handler)
+	}
+}
+
+func (r *Router) SetMethodNotFound(method string, handler fasthttp.RequestHandler) {
+	if r.methodNotFound == nil {
+		r.methodNotFound = make(map[string]fasthttp.RequestHandler)
+	}
+	r.methodNotFound[method] = handler
+}
+
+func (r *Router) Remove(method, path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resetMatchCache()
+	return r.trees.Remove(method, unescapePath(path))
+}
+
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+func (r *Router) Routes() []Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := []Route{}
+	for _, v := range *r.trees {
+		routes = append(routes, Route{Method: v.method, Path: v.path})
+	}
+	return routes
+}
+
+func (r *Router) Validate() error {
+	problems := []string{}
+	seen := map[string]bool{}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, v := range *r.trees {
+		route := v.method + " " + v.path
+		switch true {
+		case v.method == "":
+			problems = append(problems, fmt.Sprintf("%q: empty method", v.path))
+		case !strings.HasPrefix(v.path, "/"):
+			problems = append(problems, fmt.Sprintf("%s: path must begin with \"/\"", route))
+		case v.handler == nil:
+			problems = append(problems, fmt.Sprintf("%s: nil handler", route))
+		case seen[route+fmt.Sprint(v.query)]:
+			problems = append(problems, fmt.Sprintf("%s: registered more than once", route))
+		}
+		seen[route+fmt.Sprint(v.query)] = true
+	}
+	if len(problems) != 0 {
+		return fmt.Errorf("invalid routes: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (r *Router) DebugHandler() fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		body, err := json.Marshal(r.Routes())
+		if err != nil {
+			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+			return
+		}
+		ctx.SetContentType("application/json")
+		ctx.Write(body)
+	}
+}
+
+func (r *Router) IndexHandler() fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		seen := map[string]bool{}
+		ctx.SetContentType("text/html; charset=utf-8")
+		ctx.WriteString("<!DOCTYPE html>\n<ul>\n")
+		for _, route := range r.Routes() {
+			if route.Method != fasthttp.MethodGet || seen[route.Path] {
+				continue
+			}
+			seen[route.Path] = true
+			p := html.EscapeString(route.Path)
+			fmt.Fprintf(ctx, "<li><a href=\"%s\">%s</a></li>\n", p, p)
+		}
+		ctx.WriteString("</ul>\n")
+	}
+}
+
+func (r *Router) Use(middlewares ...func(fasthttp.RequestHandler) fasthttp.RequestHandler) {
+	r.middlewares = append(r.middlewares, middlewares...)
+	chain := fasthttp.RequestHandler(r.serve)
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		chain = r.middlewares[i](chain)
+	}
+	r.chain = chain
+}
+
+func (r *Router) UseFor(methods []string, middlewares ...func(fasthttp.RequestHandler) fasthttp.RequestHandler) {
+	for _, middleware := range middlewares {
+		r.Use(forMethods(methods, middleware))
+	}
+}
+
+func forMethods(methods []string, middleware func(fasthttp.RequestHandler) fasthttp.RequestHandler) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	set := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		set[strings.ToUpper(m)] = true
+	}
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		wrapped := middleware(next)
+		return func(ctx *fasthttp.RequestCtx) {
+			if set[requestMethod(ctx)] {
+				wrapped(ctx)
+			} else {
+				next(ctx)
+			}
+		}
+	}
+}
+
 func (r *Router) Handler(ctx *fasthttp.RequestCtx) {
-	if r.PanicHandler != nil {
+	defer r.count(ctx)
+	if r.PanicHandler != nil || !r.DisableDefaultRecovery {
 		defer r.recv(ctx)
 	}
+	if r.chain != nil {
+		r.chain(ctx)
+	} else {
+		r.serve(ctx)
+	}
+	r.renderError(ctx)
+}
+
+func (r *Router) renderError(ctx *fasthttp.RequestCtx) {
+	if len(r.errorHandlers) == 0 {
+		return
+	}
+	status, ok := ctx.UserValue(errorKey).(int)
+	if !ok || status != ctx.Response.StatusCode() {
+		return
+	}
+	ctx.RemoveUserValue(errorKey)
+	if handler := r.errorHandlers[status]; handler != nil {
+		ctx.Response.ResetBody()
+		handler(ctx)
+	}
+}
+
+func (r *Router) serve(ctx *fasthttp.RequestCtx) {
+	if r.MaxURILength > 0 && len(ctx.Path()) > r.MaxURILength {
+		r.writeError(ctx, "uri too long", fasthttp.StatusRequestURITooLong)
+		return
+	}
+	if r.inMaintenance(ctx) {
+		ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, strconv.Itoa(int(atomic.LoadInt32(&r.retryAfter))))
+		r.writeError(ctx, "service unavailable", fasthttp.StatusServiceUnavailable)
+		return
+	}
 	path := string(ctx.Path())
-	method := GetMethod(ctx)
-	if nodeFindByPath := r.trees.FindPath(path); nodeFindByPath.Len() != 0 {
-		if node := nodeFindByPath.FindMethod(method); node != nil {
-			handler := node.GetHandler()
-			handler(ctx)
+	method := requestMethod(ctx)
+	node, nodeFindByPath := r.resolve(method, path, ctx.QueryArgs())
+	if nodeFindByPath.Len() != 0 {
+		if node != nil {
+			r.call(ctx, node.GetHandler())
 		} else {
-			if node := nodeFindByPath.GetMethodAll(); node != nil {
-				handler := node.GetHandler()
-				handler(ctx)
+			ctx.Response.Header.Set(fasthttp.HeaderAllow, strings.Join(nodeFindByPath.Methods(), ", "))
+			r.noMatch(ctx, "method not allowed")
+			if r.MethodNotAllowed != nil {
+				r.MethodNotAllowed(ctx)
 			} else {
-				if r.MethodNotAllowed != nil {
-					r.MethodNotAllowed(ctx)
-				} else {
-					ctx.Error("method not allowed", fasthttp.StatusMethodNotAllowed)
-				}
+				r.writeError(ctx, "method not allowed", fasthttp.StatusMethodNotAllowed)
 			}
 		}
+	} else if proxy := r.findProxy(path); proxy != nil {
+		proxy(ctx)
 	} else {
-		if r.NotFound != nil {
+		if r.Debug {
+			if r.findPath(path).Len() != 0 {
+				r.noMatch(ctx, "query mismatch")
+			} else {
+				r.noMatch(ctx, "no route")
+			}
+		}
+		if handler := r.methodNotFound[method]; handler != nil {
+			handler(ctx)
+		} else if r.NotFound != nil {
 			r.NotFound(ctx)
 		} else {
-			ctx.Error(fmt.Sprintf("%s %s not found", method, path), fasthttp.StatusNotFound)
+			r.writeError(ctx, fmt.Sprintf("%s %s not found", method, path), fasthttp.StatusNotFound)
 		}
 	}
 }
 
+func (r *Router) noMatch(ctx *fasthttp.RequestCtx, reason string) {
+	if r.Debug {
+		ctx.SetUserValue(noMatchKey, reason)
+		ctx.Response.Header.Set("X-Ming-NoMatch-Reason", reason)
+	}
+}
+
+func (r *Router) EnableMatchCache(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if size > 0 {
+		r.matchCache = newLRU[*Tree](size)
+	} else {
+		r.matchCache = nil
+	}
+}
+
+func (r *Router) resetMatchCache() {
+	if r.matchCache != nil {
+		r.matchCache = newLRU[*Tree](r.matchCache.capacity)
+	}
+}
+
+func (r *Router) findPath(path string) *Tree {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.matchCache == nil {
+		return r.trees.FindPath(path)
+	}
+	if nodes, ok := r.matchCache.Get(path); ok {
+		return nodes
+	}
+	nodes := r.trees.FindPath(path)
+	r.matchCache.Set(path, nodes)
+	return nodes
+}
+
+func (r *Router) resolve(method, path string, args *fasthttp.Args) (*Node, *Tree) {
+	nodes := r.findPath(path).MatchQuery(args)
+	if node := nodes.FindMethod(method); node != nil {
+		return node, nodes
+	}
+	return nodes.GetMethodAll(), nodes
+}
+
+type TestRequest struct {
+	Method string
+	Path   string
+}
+
+type MatchResult struct {
+	Method  string
+	Path    string
+	Status  int
+	Route   string
+	Allowed []string
+}
+
+func (r *Router) MatchAll(requests []TestRequest) []MatchResult {
+	results := make([]MatchResult, 0, len(requests))
+	for _, req := range requests {
+		uri := fasthttp.AcquireURI()
+		uri.Parse(nil, []byte(req.Path))
+		node, nodes := r.resolve(strings.ToUpper(req.Method), string(uri.Path()), uri.QueryArgs())
+		result := MatchResult{Method: req.Method, Path: req.Path}
+		switch true {
+		case node != nil:
+			result.Status = fasthttp.StatusOK
+			result.Route = node.method + " " + node.path
+		case nodes.Len() != 0:
+			result.Status = fasthttp.StatusMethodNotAllowed
+			result.Allowed = nodes.Methods()
+		default:
+			result.Status = fasthttp.StatusNotFound
+		}
+		fasthttp.ReleaseURI(uri)
+		results = append(results, result)
+	}
+	return results
+}
+
+func (r *Router) SetErrorHandler(status int, handler fasthttp.RequestHandler) {
+	if r.errorHandlers == nil {
+		r.errorHandlers = make(map[int]fasthttp.RequestHandler)
+	}
+	r.errorHandlers[status] = handler
+}
+
+func (r *Router) writeError(ctx *fasthttp.RequestCtx, msg string, statusCode int) {
+	if handler := r.errorHandlers[statusCode]; handler != nil {
+		ctx.Response.ResetBody()
+		ctx.SetStatusCode(statusCode)
+		handler(ctx)
+	} else {
+		writeError(ctx, msg, statusCode)
+	}
+}
+
+func (r *Router) call(ctx *fasthttp.RequestCtx, handler fasthttp.RequestHandler) {
+	handler(ctx)
+	if status := ctx.Response.StatusCode(); status >= 400 && !ctx.Response.IsBodyStream() && len(ctx.Response.Body()) == 0 {
+		if handler := r.errorHandlers[status]; handler != nil {
+			handler(ctx)
+		}
+	}
+	if r.AutoNoContent && ctx.Response.StatusCode() == fasthttp.StatusOK &&
+		!ctx.Response.IsBodyStream() && len(ctx.Response.Body()) == 0 {
+		ctx.SetStatusCode(fasthttp.StatusNoContent)
+	}
+}
+
 func (r *Router) Get(path string, handler fasthttp.RequestHandler) {
 	r.Handle(fasthttp.MethodGet, path, handler)
 }
@@ -84,15 +404,200 @@ func (r *Router) Options(path string, handler fasthttp.RequestHandler) {
 func (r *Router) Trace(path string, handler fasthttp.RequestHandler) {
 	r.Handle(fasthttp.MethodTrace, path, handler)
 }
+
 func (r *Router) All(path string, handler fasthttp.RequestHandler) {
 	r.Handle("ALL", path, handler)
 }
 
+type HandlerE func(*fasthttp.RequestCtx) error
+
+func (r *Router) HandleE(method, path string, handler HandlerE) {
+	r.addNode(&Node{
+		method:   method,
+		path:     path,
+		handler:  wrapE(handler, r.handleError),
+		handlerE: handler,
+	})
+}
+
+func wrapE(handler HandlerE, onError func(*fasthttp.RequestCtx, error)) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if err := callE(ctx, handler); err != nil {
+			onError(ctx, err)
+		}
+	}
+}
+
+func callE(ctx *fasthttp.RequestCtx, handler HandlerE) (err error) {
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			log.Printf("ming: panic serving %s %s: %v", ctx.Method(), ctx.Path(), rcv)
+			if e, ok := rcv.(error); ok {
+				err = fmt.Errorf("panic: %w", e)
+			} else {
+				err = fmt.Errorf("panic: %v", rcv)
+			}
+		}
+	}()
+	return handler(ctx)
+}
+
+func (r *Router) handleError(ctx *fasthttp.RequestCtx, err error) {
+	if r.ErrorHandler != nil {
+		r.ErrorHandler(ctx, err)
+	} else {
+		defaultErrorHandler(ctx, err)
+	}
+}
+
+func defaultErrorHandler(ctx *fasthttp.RequestCtx, err error) {
+	ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	ctx.SetContentType("application/json")
+	ctx.SetBodyString(`{"error":"internal server error"}`)
+}
+
+func (r *Router) GetE(path string, handler HandlerE) {
+	r.HandleE(fasthttp.MethodGet, path, handler)
+}
+
+func (r *Router) PostE(path string, handler HandlerE) {
+	r.HandleE(fasthttp.MethodPost, path, handler)
+}
+
+func (r *Router) PutE(path string, handler HandlerE) {
+	r.HandleE(fasthttp.MethodPut, path, handler)
+}
+
+func (r *Router) PatchE(path string, handler HandlerE) {
+	r.HandleE(fasthttp.MethodPatch, path, handler)
+}
+
+func (r *Router) DeleteE(path string, handler HandlerE) {
+	r.HandleE(fasthttp.MethodDelete, path, handler)
+}
+
+func (r *Router) AllE(path string, handler HandlerE) {
+	r.HandleE("ALL", path, handler)
+}
+
+var standardMethods = []string{
+	fasthttp.MethodGet,
+	fasthttp.MethodHead,
+	fasthttp.MethodPost,
+	fasthttp.MethodPut,
+	fasthttp.MethodPatch,
+	fasthttp.MethodDelete,
+	fasthttp.MethodConnect,
+	fasthttp.MethodOptions,
+	fasthttp.MethodTrace,
+}
+
+func (r *Router) AllExcept(path string, exclude []string, handler fasthttp.RequestHandler) {
+	excluded := make(map[string]bool, len(exclude))
+	for _, m := range exclude {
+		excluded[strings.ToUpper(m)] = true
+	}
+	for _, m := range standardMethods {
+		if !excluded[m] {
+			r.Handle(m, path, handler)
+		}
+	}
+}
+
+type StaticConfig struct {
+	Root            string
+	IndexPage       bool
+	AcceptByteRange bool
+	Precompressed   bool
+	NotFoundFile    string
+}
+
 func (r *Router) Static(rootPath string, IsIndexPage bool) {
+	r.StaticWithConfig(StaticConfig{
+		Root:      rootPath,
+		IndexPage: IsIndexPage,
+	})
+}
+
+func (r *Router) StaticWithConfig(config StaticConfig) {
 	fs := &fasthttp.FS{
-		Root:               rootPath,
+		Root:               config.Root,
 		IndexNames:         []string{"index.html"},
-		GenerateIndexPages: IsIndexPage,
+		GenerateIndexPages: config.IndexPage,
+		AcceptByteRange:    config.AcceptByteRange,
+	}
+	if config.NotFoundFile != "" {
+		notFound := filepath.Join(config.Root, config.NotFoundFile)
+		fs.PathNotFound = func(ctx *fasthttp.RequestCtx) {
+			fasthttp.ServeFileUncompressed(ctx, notFound)
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+		}
+	}
+	if config.Precompressed {
+		r.NotFound = servePrecompressed(config.Root, fs.NewRequestHandler())
+	} else {
+		r.NotFound = fs.NewRequestHandler()
+	}
+}
+
+func servePrecompressed(root string, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if !ctx.Request.Header.HasAcceptEncoding("gzip") {
+			next(ctx)
+			return
+		}
+		name := filepath.Join(root, filepath.FromSlash(path.Clean("/"+string(ctx.Path()))))
+		if info, err := os.Stat(name + ".gz"); err != nil || info.IsDir() {
+			next(ctx)
+			return
+		}
+		fasthttp.ServeFileUncompressed(ctx, name+".gz")
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			return
+		}
+		if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+			ctx.SetContentType(contentType)
+		} else {
+			ctx.SetContentType("application/octet-stream")
+		}
+		ctx.Response.Header.Set(fasthttp.HeaderContentEncoding, "gzip")
+		ctx.Response.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderAcceptEncoding)
+	}
+}
+
+func (r *Router) ServeTest(method, uri string, body []byte) *fasthttp.Response {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	req.SetBody(body)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(req, nil, nil)
+	r.Handler(ctx)
+	return &ctx.Response
+}
+
+func (r *Router) SPA(rootDir, indexFile string) {
+	index := filepath.Join(rootDir, indexFile)
+	fs := &fasthttp.FS{
+		Root:       rootDir,
+		IndexNames: []string{indexFile},
+		PathNotFound: func(ctx *fasthttp.RequestCtx) {
+			if !ctx.IsGet() && !ctx.IsHead() {
+				writeError(ctx, "not found", fasthttp.StatusNotFound)
+				return
+			}
+			fasthttp.ServeFile(ctx, index)
+		},
 	}
 	r.NotFound = fs.NewRequestHandler()
 }
+
+func (r *Router) Favicon(path string) {
+	r.Get("/favicon.ico", func(ctx *fasthttp.RequestCtx) {
+		if path == "" {
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+			return
+		}
+		fasthttp.ServeFile(ctx, path)
+	})
+}
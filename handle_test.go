@@ -0,0 +1,726 @@
+package ming
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func ok(ctx *fasthttp.RequestCtx) {
+	ctx.WriteString("ok")
+}
+
+func TestGetMethodUnknown(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("PURGE")
+	if method := GetMethod(ctx); method != "" {
+		t.Fatalf("GetMethod = %q, want empty", method)
+	}
+}
+
+func TestCustomMethod(t *testing.T) {
+	r := New()
+	r.Handle("PURGE", "/cache", ok)
+	if resp := r.ServeTest("PURGE", "/cache", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("PURGE status = %d, want 200", resp.StatusCode())
+	}
+}
+
+func TestHeadMethod(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodHead)
+	if method := GetMethod(ctx); method != fasthttp.MethodHead {
+		t.Fatalf("GetMethod = %q, want HEAD", method)
+	}
+
+	r := New()
+	r.Head("/h", ok)
+	r.AllExcept("/all", []string{fasthttp.MethodPatch}, ok)
+	if resp := r.ServeTest(fasthttp.MethodHead, "/h", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("HEAD /h status = %d, want 200", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodHead, "/all", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("HEAD /all status = %d, want 200", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodPatch, "/all", nil); resp.StatusCode() != fasthttp.StatusMethodNotAllowed {
+		t.Fatalf("PATCH /all status = %d, want 405", resp.StatusCode())
+	}
+}
+
+func TestHeadUseForAndCSRF(t *testing.T) {
+	r := New()
+	ran := false
+	r.UseFor([]string{fasthttp.MethodHead}, func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ran = true
+			next(ctx)
+		}
+	})
+	r.Use(CSRF(CSRFOptions{}))
+	r.Head("/h", ok)
+	if resp := r.ServeTest(fasthttp.MethodHead, "/h", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("HEAD status = %d, want 200", resp.StatusCode())
+	}
+	if !ran {
+		t.Fatal("UseFor HEAD middleware did not run")
+	}
+}
+
+func TestHandlerEPanic(t *testing.T) {
+	r := New()
+	r.GetE("/panic", func(ctx *fasthttp.RequestCtx) error {
+		var m map[string]int
+		m["x"] = 1
+		return nil
+	})
+	resp := r.ServeTest(fasthttp.MethodGet, "/panic", nil)
+	if resp.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", resp.StatusCode())
+	}
+	if body := string(resp.Body()); body != `{"error":"internal server error"}` {
+		t.Fatalf("body = %s", body)
+	}
+
+	var got error
+	r.ErrorHandler = func(ctx *fasthttp.RequestCtx, err error) {
+		got = err
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+	}
+	resp = r.ServeTest(fasthttp.MethodGet, "/panic", nil)
+	if resp.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("custom handler status = %d, want 503", resp.StatusCode())
+	}
+	if got == nil || !strings.Contains(got.Error(), "panic") || !strings.Contains(got.Error(), "nil map") {
+		t.Fatalf("ErrorHandler received %v, want the panic", got)
+	}
+}
+
+func TestConcurrentRegistration(t *testing.T) {
+	r := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/route/%d", i)
+			r.Get(path, ok)
+			r.ServeTest(fasthttp.MethodGet, path, nil)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 50; i++ {
+		if resp := r.ServeTest(fasthttp.MethodGet, fmt.Sprintf("/route/%d", i), nil); resp.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("/route/%d status = %d, want 200", i, resp.StatusCode())
+		}
+	}
+}
+
+func TestServeTest(t *testing.T) {
+	r := New()
+	r.Post("/echo", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.Write(ctx.Method())
+		ctx.Write(ctx.Request.Body())
+	})
+	resp := r.ServeTest(fasthttp.MethodPost, "/echo?x=1", []byte("-body"))
+	if resp.StatusCode() != fasthttp.StatusCreated {
+		t.Fatalf("status = %d, want 201", resp.StatusCode())
+	}
+	if got := string(resp.Body()); got != "POST-body" {
+		t.Fatalf("body = %q", got)
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/missing", nil); resp.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("missing route status = %d, want 404", resp.StatusCode())
+	}
+}
+
+func newBenchRouter(cacheSize int) *Router {
+	r := New()
+	for i := 0; i < 1000; i++ {
+		r.Get(fmt.Sprintf("/route/%d", i), ok)
+	}
+	r.EnableMatchCache(cacheSize)
+	return r
+}
+
+func TestMatchCache(t *testing.T) {
+	for _, size := range []int{0, 16} {
+		r := newBenchRouter(size)
+		for i := 0; i < 2; i++ {
+			if resp := r.ServeTest(fasthttp.MethodGet, "/route/999", nil); resp.StatusCode() != fasthttp.StatusOK {
+				t.Fatalf("cache %d: status = %d, want 200", size, resp.StatusCode())
+			}
+			if resp := r.ServeTest(fasthttp.MethodPost, "/route/999", nil); resp.StatusCode() != fasthttp.StatusMethodNotAllowed {
+				t.Fatalf("cache %d: POST status = %d, want 405", size, resp.StatusCode())
+			}
+		}
+		r.Remove(fasthttp.MethodGet, "/route/999")
+		if resp := r.ServeTest(fasthttp.MethodGet, "/route/999", nil); resp.StatusCode() != fasthttp.StatusNotFound {
+			t.Fatalf("cache %d: removed route status = %d, want 404", size, resp.StatusCode())
+		}
+	}
+}
+
+func benchmarkMatch(b *testing.B, cacheSize int) {
+	r := newBenchRouter(cacheSize)
+	ctx := newCtx(fasthttp.MethodGet, "/route/999")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx.Response.Reset()
+		r.Handler(ctx)
+	}
+}
+
+func BenchmarkMatchNoCache(b *testing.B) {
+	benchmarkMatch(b, 0)
+}
+
+func BenchmarkMatchCache(b *testing.B) {
+	benchmarkMatch(b, 16)
+}
+
+func TestUseWrapsEveryResponse(t *testing.T) {
+	r := New()
+	r.MaxURILength = 16
+	r.Use(func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Set("X-Request-Id", "abc")
+			next(ctx)
+		}
+	})
+	r.Get("/", ok)
+	r.Get("/ok", ok)
+	r.MaintenanceAllow = []string{"/ok"}
+
+	check := func(uri string, status int) {
+		t.Helper()
+		resp := r.ServeTest(fasthttp.MethodGet, uri, nil)
+		if resp.StatusCode() != status {
+			t.Fatalf("%s status = %d, want %d", uri, resp.StatusCode(), status)
+		}
+		if got := string(resp.Header.Peek("X-Request-Id")); got != "abc" {
+			t.Fatalf("%s missing middleware header", uri)
+		}
+	}
+	check("/", fasthttp.StatusOK)
+	check("/missing", fasthttp.StatusNotFound)
+	check("/a-very-long-path-indeed", fasthttp.StatusRequestURITooLong)
+	r.SetMaintenance(true, 30)
+	check("/", fasthttp.StatusServiceUnavailable)
+	check("/ok", fasthttp.StatusOK)
+}
+
+func TestRemove(t *testing.T) {
+	r := New()
+	r.GetQuery("/items", map[string]string{"view": "full"}, ok)
+	r.Get("/items", ok)
+	r.Post("/items", ok)
+	r.Get("/other", ok)
+
+	if !r.Remove(fasthttp.MethodGet, "/items") {
+		t.Fatal("Remove returned false for an existing route")
+	}
+	for _, uri := range []string{"/items", "/items?view=full"} {
+		if resp := r.ServeTest(fasthttp.MethodGet, uri, nil); resp.StatusCode() != fasthttp.StatusMethodNotAllowed {
+			t.Fatalf("GET %s status = %d, want 405", uri, resp.StatusCode())
+		}
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/items", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("POST /items status = %d, want 200", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/other", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("GET /other status = %d, want 200", resp.StatusCode())
+	}
+	if r.Remove(fasthttp.MethodGet, "/items") {
+		t.Fatal("Remove returned true for a missing route")
+	}
+}
+
+func TestSetErrorHandler(t *testing.T) {
+	r := New()
+	r.SetErrorHandler(fasthttp.StatusNotFound, func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("branded 404")
+	})
+	r.SetErrorHandler(fasthttp.StatusInternalServerError, func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("branded 500")
+	})
+	r.SetErrorHandler(fasthttp.StatusForbidden, func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("branded 403")
+	})
+	r.Get("/panic", func(ctx *fasthttp.RequestCtx) { panic("boom") })
+	r.Get("/empty", func(ctx *fasthttp.RequestCtx) { ctx.SetStatusCode(fasthttp.StatusInternalServerError) })
+	r.Get("/body", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		ctx.WriteString("own body")
+	})
+	r.Post("/form", CSRF(CSRFOptions{})(ok))
+
+	cases := []struct {
+		method, uri string
+		status      int
+		body        string
+	}{
+		{fasthttp.MethodGet, "/missing", fasthttp.StatusNotFound, "branded 404"},
+		{fasthttp.MethodGet, "/panic", fasthttp.StatusInternalServerError, "branded 500"},
+		{fasthttp.MethodGet, "/empty", fasthttp.StatusInternalServerError, "branded 500"},
+		{fasthttp.MethodGet, "/body", fasthttp.StatusInternalServerError, "own body"},
+		{fasthttp.MethodPost, "/form", fasthttp.StatusForbidden, "branded 403"},
+	}
+	for _, c := range cases {
+		resp := r.ServeTest(c.method, c.uri, nil)
+		if resp.StatusCode() != c.status || string(resp.Body()) != c.body {
+			t.Errorf("%s %s = %d %q, want %d %q", c.method, c.uri, resp.StatusCode(), resp.Body(), c.status, c.body)
+		}
+	}
+}
+
+func TestGetQuery(t *testing.T) {
+	named := func(name string) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) { ctx.WriteString(name) }
+	}
+	r := New()
+	r.GetQuery("/search", map[string]string{"type": "image"}, named("image"))
+	r.GetQuery("/search", map[string]string{"type": "video", "hd": "1"}, named("hd video"))
+	r.Get("/search", named("fallback"))
+	r.GetQuery("/only", map[string]string{"type": "image"}, named("only"))
+
+	cases := []struct {
+		uri    string
+		status int
+		body   string
+	}{
+		{"/search?type=image", fasthttp.StatusOK, "image"},
+		{"/search?hd=1&type=video", fasthttp.StatusOK, "hd video"},
+		{"/search?type=video", fasthttp.StatusOK, "fallback"},
+		{"/search", fasthttp.StatusOK, "fallback"},
+		{"/only?type=image", fasthttp.StatusOK, "only"},
+		{"/only?type=video", fasthttp.StatusNotFound, ""},
+	}
+	for _, c := range cases {
+		resp := r.ServeTest(fasthttp.MethodGet, c.uri, nil)
+		if resp.StatusCode() != c.status || (c.body != "" && string(resp.Body()) != c.body) {
+			t.Errorf("%s = %d %q, want %d %q", c.uri, resp.StatusCode(), resp.Body(), c.status, c.body)
+		}
+	}
+}
+
+func TestPercentEncodedRoutes(t *testing.T) {
+	r := New()
+	r.Get("/caf%C3%A9", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("encoded") })
+	r.Get("/naïve", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("decoded") })
+	r.Get("/files/a%2Fb", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("slash") })
+
+	cases := map[string]string{
+		"/café":        "encoded",
+		"/caf%C3%A9":   "encoded",
+		"/naïve":       "decoded",
+		"/na%C3%AFve":  "decoded",
+		"/files/a%2Fb": "slash",
+		"/files/a/b":   "slash",
+		"/files/a%2fb": "slash",
+	}
+	for uri, want := range cases {
+		resp := r.ServeTest(fasthttp.MethodGet, uri, nil)
+		if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != want {
+			t.Errorf("%s = %d %q, want %q", uri, resp.StatusCode(), resp.Body(), want)
+		}
+	}
+}
+
+func TestMethodNotAllowedAllowHeader(t *testing.T) {
+	r := New()
+	r.Get("/items", ok)
+	r.Post("/items", ok)
+	r.AllExcept("/except", []string{fasthttp.MethodDelete}, ok)
+
+	resp := r.ServeTest(fasthttp.MethodPut, "/items", nil)
+	if resp.StatusCode() != fasthttp.StatusMethodNotAllowed {
+		t.Fatalf("PUT /items status = %d, want 405", resp.StatusCode())
+	}
+	if got := string(resp.Header.Peek(fasthttp.HeaderAllow)); got != "GET, POST" {
+		t.Fatalf("Allow = %q, want %q", got, "GET, POST")
+	}
+
+	resp = r.ServeTest(fasthttp.MethodDelete, "/except", nil)
+	if resp.StatusCode() != fasthttp.StatusMethodNotAllowed {
+		t.Fatalf("DELETE /except status = %d, want 405", resp.StatusCode())
+	}
+	if got, want := string(resp.Header.Peek(fasthttp.HeaderAllow)), "GET, HEAD, POST, PUT, PATCH, CONNECT, OPTIONS, TRACE"; got != want {
+		t.Fatalf("Allow = %q, want %q", got, want)
+	}
+}
+
+func TestDebugNoMatchReason(t *testing.T) {
+	r := New()
+	r.Get("/items", ok)
+	r.GetQuery("/search", map[string]string{"type": "image"}, ok)
+
+	cases := []struct {
+		method, uri, reason string
+	}{
+		{fasthttp.MethodGet, "/search?type=video", "query mismatch"},
+		{fasthttp.MethodGet, "/unknown", "no route"},
+		{fasthttp.MethodPost, "/items", "method not allowed"},
+		{fasthttp.MethodGet, "/items", ""},
+	}
+	for _, debug := range []bool{true, false} {
+		r.Debug = debug
+		for _, c := range cases {
+			want := c.reason
+			if !debug {
+				want = ""
+			}
+			ctx := newCtx(c.method, c.uri)
+			r.Handler(ctx)
+			if got := string(ctx.Response.Header.Peek("X-Ming-NoMatch-Reason")); got != want {
+				t.Errorf("debug=%v %s %s header = %q, want %q", debug, c.method, c.uri, got, want)
+			}
+			if got := NoMatchReason(ctx); got != want {
+				t.Errorf("debug=%v %s %s NoMatchReason = %q, want %q", debug, c.method, c.uri, got, want)
+			}
+		}
+	}
+}
+
+func TestMaxURILength(t *testing.T) {
+	r := New()
+	r.MaxURILength = 16
+	r.Get("/short", ok)
+	r.Get("/"+strings.Repeat("x", 20), ok)
+
+	if resp := r.ServeTest(fasthttp.MethodGet, "/short", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("short path status = %d, want 200", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/"+strings.Repeat("x", 20), nil); resp.StatusCode() != fasthttp.StatusRequestURITooLong {
+		t.Fatalf("long path status = %d, want 414", resp.StatusCode())
+	}
+}
+
+func TestStaticByteRange(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "media.txt"), []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.StaticWithConfig(StaticConfig{Root: dir, AcceptByteRange: true})
+
+	ctx := newCtx(fasthttp.MethodGet, "/media.txt")
+	ctx.Request.Header.Set(fasthttp.HeaderRange, "bytes=0-3")
+	r.Handler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", ctx.Response.StatusCode())
+	}
+	if got := string(ctx.Response.Header.Peek(fasthttp.HeaderContentRange)); got != "bytes 0-3/10" {
+		t.Fatalf("Content-Range = %q", got)
+	}
+	if got := string(ctx.Response.Body()); got != "0123" {
+		t.Fatalf("body = %q, want %q", got, "0123")
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	r := New()
+	r.Get("/users", ok)
+	r.Post("/users", ok)
+	r.Get("/debug/routes", r.DebugHandler())
+
+	resp := r.ServeTest(fasthttp.MethodGet, "/debug/routes", nil)
+	if got := string(resp.Header.ContentType()); got != "application/json" {
+		t.Fatalf("content type = %q", got)
+	}
+	var routes []Route
+	if err := json.Unmarshal(resp.Body(), &routes); err != nil {
+		t.Fatalf("invalid JSON %q: %v", resp.Body(), err)
+	}
+	want := []Route{{"GET", "/users"}, {"POST", "/users"}, {"GET", "/debug/routes"}}
+	if fmt.Sprint(routes) != fmt.Sprint(want) {
+		t.Fatalf("routes = %v, want %v", routes, want)
+	}
+}
+
+func TestHandleBothSlash(t *testing.T) {
+	r := New()
+	r.HandleBothSlash(fasthttp.MethodGet, "/users", ok)
+	r.HandleBothSlash(fasthttp.MethodGet, "/docs/", ok)
+
+	for _, uri := range []string{"/users", "/users/", "/docs", "/docs/"} {
+		resp := r.ServeTest(fasthttp.MethodGet, uri, nil)
+		if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "ok" {
+			t.Fatalf("%s: %d %q, want 200 without redirect", uri, resp.StatusCode(), resp.Body())
+		}
+		if location := resp.Header.Peek(fasthttp.HeaderLocation); len(location) != 0 {
+			t.Fatalf("%s redirected to %q", uri, location)
+		}
+	}
+}
+
+func TestSPA(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<app>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.SPA(dir, "index.html")
+
+	if resp := r.ServeTest(fasthttp.MethodGet, "/app.js", nil); resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "console.log(1)" {
+		t.Fatalf("asset: %d %q", resp.StatusCode(), resp.Body())
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/users/42", nil); resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "<app>" {
+		t.Fatalf("client route: %d %q, want index", resp.StatusCode(), resp.Body())
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/users/42", nil); resp.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("POST client route status = %d, want 404", resp.StatusCode())
+	}
+}
+
+func TestGetE(t *testing.T) {
+	r := New()
+	r.GetE("/ok", func(ctx *fasthttp.RequestCtx) error {
+		ctx.WriteString("done")
+		return nil
+	})
+	r.GetE("/fail", func(ctx *fasthttp.RequestCtx) error {
+		return errors.New("database unavailable")
+	})
+
+	if resp := r.ServeTest(fasthttp.MethodGet, "/ok", nil); resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "done" {
+		t.Fatalf("nil error: %d %q", resp.StatusCode(), resp.Body())
+	}
+	resp := r.ServeTest(fasthttp.MethodGet, "/fail", nil)
+	if resp.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Fatalf("error status = %d, want 500", resp.StatusCode())
+	}
+	if got := string(resp.Header.ContentType()); got != "application/json" {
+		t.Fatalf("error content type = %q", got)
+	}
+	if got := string(resp.Body()); got != `{"error":"internal server error"}` {
+		t.Fatalf("error body = %s", got)
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	plain := "body { color: red }"
+	if err := os.WriteFile(filepath.Join(dir, "app.css"), []byte(plain), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.css.gz"), fasthttp.AppendGzipBytes(nil, []byte(plain)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.StaticWithConfig(StaticConfig{Root: dir, Precompressed: true})
+
+	ctx := newCtx(fasthttp.MethodGet, "/app.css")
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	r.Handler(ctx)
+	if got := string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := string(ctx.Response.Header.ContentType()); !strings.HasPrefix(got, "text/css") {
+		t.Fatalf("content type = %q", got)
+	}
+	body, err := ctx.Response.BodyGunzip()
+	if err != nil || string(body) != plain {
+		t.Fatalf("gunzipped body = %q, %v", body, err)
+	}
+
+	resp := r.ServeTest(fasthttp.MethodGet, "/app.css", nil)
+	if len(resp.Header.Peek(fasthttp.HeaderContentEncoding)) != 0 || string(resp.Body()) != plain {
+		t.Fatalf("without Accept-Encoding: %q %q", resp.Header.Peek(fasthttp.HeaderContentEncoding), resp.Body())
+	}
+}
+
+func TestAutoNoContent(t *testing.T) {
+	r := New()
+	r.AutoNoContent = true
+	r.Delete("/items", func(ctx *fasthttp.RequestCtx) {})
+	r.Get("/items", ok)
+	r.Post("/items", func(ctx *fasthttp.RequestCtx) { ctx.SetStatusCode(fasthttp.StatusAccepted) })
+
+	if resp := r.ServeTest(fasthttp.MethodDelete, "/items", nil); resp.StatusCode() != fasthttp.StatusNoContent {
+		t.Fatalf("empty body status = %d, want 204", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/items", nil); resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "ok" {
+		t.Fatalf("body status = %d %q, want 200", resp.StatusCode(), resp.Body())
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/items", nil); resp.StatusCode() != fasthttp.StatusAccepted {
+		t.Fatalf("explicit status = %d, want 202", resp.StatusCode())
+	}
+
+	r.AutoNoContent = false
+	if resp := r.ServeTest(fasthttp.MethodDelete, "/items", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("disabled status = %d, want 200", resp.StatusCode())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	r := New()
+	r.Get("/users", ok)
+	r.Post("/users", ok)
+	r.GetQuery("/users", map[string]string{"active": "1"}, ok)
+	if err := r.Validate(); err != nil {
+		t.Fatalf("valid table: %v", err)
+	}
+
+	r.Get("/users", ok)
+	r.Get("/nil", nil)
+	r.Handle("", "/empty", ok)
+	r.trees.Add(&Node{method: fasthttp.MethodGet, path: "items", handler: ok})
+	err := r.Validate()
+	if err == nil {
+		t.Fatal("broken table validated")
+	}
+	for _, want := range []string{"GET /users: registered more than once", `GET items: path must begin with "/"`, "GET /nil: nil handler", `"/empty": empty method`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestSetMethodNotFound(t *testing.T) {
+	r := New()
+	r.Get("/", ok)
+	r.SetMethodNotFound(fasthttp.MethodGet, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		ctx.WriteString("get miss")
+	})
+	r.NotFound = func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		ctx.WriteString("global miss")
+	}
+
+	if resp := r.ServeTest(fasthttp.MethodGet, "/missing", nil); string(resp.Body()) != "get miss" {
+		t.Fatalf("GET miss body = %q, want GET-specific handler", resp.Body())
+	}
+	if resp := r.ServeTest(fasthttp.MethodPut, "/missing", nil); string(resp.Body()) != "global miss" {
+		t.Fatalf("PUT miss body = %q, want global handler", resp.Body())
+	}
+}
+
+func TestStaticNotFoundFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "404.html"), []byte("<h1>missing</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.StaticWithConfig(StaticConfig{Root: dir, NotFoundFile: "404.html"})
+
+	resp := r.ServeTest(fasthttp.MethodGet, "/nope.html", nil)
+	if resp.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("status = %d, want 404", resp.StatusCode())
+	}
+	if got := string(resp.Body()); got != "<h1>missing</h1>" {
+		t.Fatalf("body = %q, want the 404 file", got)
+	}
+}
+
+func TestIndexHandler(t *testing.T) {
+	r := New()
+	r.Get("/", r.IndexHandler())
+	r.Get("/about", ok)
+	r.Head("/about", ok)
+	r.Get("/tom&jerry", ok)
+	r.Post("/submit", ok)
+
+	resp := r.ServeTest(fasthttp.MethodGet, "/", nil)
+	body := string(resp.Body())
+	for _, want := range []string{`<a href="/about">/about</a>`, `<a href="/">/</a>`, `<a href="/tom&amp;jerry">`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("index missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "/submit") {
+		t.Errorf("index lists a POST route:\n%s", body)
+	}
+	if strings.Count(body, `href="/about"`) != 1 {
+		t.Errorf("index lists /about more than once:\n%s", body)
+	}
+}
+
+func TestMiddlewareAbort(t *testing.T) {
+	called := false
+	r := New()
+	r.Use(func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if len(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization)) == 0 {
+				ctx.Error("unauthorized", fasthttp.StatusUnauthorized)
+				return
+			}
+			next(ctx)
+		}
+	})
+	r.Get("/secret", func(ctx *fasthttp.RequestCtx) {
+		called = true
+		ok(ctx)
+	})
+
+	resp := r.ServeTest(fasthttp.MethodGet, "/secret", nil)
+	if resp.StatusCode() != fasthttp.StatusUnauthorized || called {
+		t.Fatalf("aborted request: status %d, handler called %v", resp.StatusCode(), called)
+	}
+	ctx := newCtx(fasthttp.MethodGet, "/secret")
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, "token")
+	r.Handler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK || !called {
+		t.Fatalf("authorized request: status %d, handler called %v", ctx.Response.StatusCode(), called)
+	}
+}
+
+func TestFavicon(t *testing.T) {
+	icon := filepath.Join(t.TempDir(), "favicon.ico")
+	if err := os.WriteFile(icon, []byte("icon-bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Favicon(icon)
+	resp := r.ServeTest(fasthttp.MethodGet, "/favicon.ico", nil)
+	if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "icon-bytes" {
+		t.Fatalf("favicon file: %d %q", resp.StatusCode(), resp.Body())
+	}
+
+	r = New()
+	r.Favicon("")
+	resp = r.ServeTest(fasthttp.MethodGet, "/favicon.ico", nil)
+	if resp.StatusCode() != fasthttp.StatusNoContent || len(resp.Body()) != 0 {
+		t.Fatalf("silent favicon: %d %q, want 204", resp.StatusCode(), resp.Body())
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	called := false
+	handler := func(ctx *fasthttp.RequestCtx) { called = true }
+	r := New()
+	r.Get("/users", handler)
+	r.Post("/users", handler)
+	r.GetQuery("/search", map[string]string{"type": "image"}, handler)
+
+	results := r.MatchAll([]TestRequest{
+		{Method: "get", Path: "/users"},
+		{Method: fasthttp.MethodDelete, Path: "/users"},
+		{Method: fasthttp.MethodGet, Path: "/missing"},
+		{Method: fasthttp.MethodGet, Path: "/search?type=image"},
+	})
+	want := []MatchResult{
+		{Method: "get", Path: "/users", Status: fasthttp.StatusOK, Route: "GET /users"},
+		{Method: fasthttp.MethodDelete, Path: "/users", Status: fasthttp.StatusMethodNotAllowed, Allowed: []string{"GET", "POST"}},
+		{Method: fasthttp.MethodGet, Path: "/missing", Status: fasthttp.StatusNotFound},
+		{Method: fasthttp.MethodGet, Path: "/search?type=image", Status: fasthttp.StatusOK, Route: "GET /search"},
+	}
+	if fmt.Sprintf("%+v", results) != fmt.Sprintf("%+v", want) {
+		t.Fatalf("results = %+v\nwant %+v", results, want)
+	}
+	if called {
+		t.Fatal("MatchAll executed a handler")
+	}
+}
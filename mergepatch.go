@@ -0,0 +1,44 @@
+package ming
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+func MergePatch(original []byte, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if len(bytes.TrimSpace(original)) != 0 {
+		if err := decodeJSON(original, &target); err != nil {
+			return nil, err
+		}
+	}
+	if err := decodeJSON(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}
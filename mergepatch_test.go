@@ -0,0 +1,33 @@
+package ming
+
+import "testing"
+
+func TestMergePatch(t *testing.T) {
+	cases := []struct {
+		name, original, patch, want string
+	}{
+		{"add field", `{"name":"alice"}`, `{"age":30}`, `{"age":30,"name":"alice"}`},
+		{"overwrite field", `{"name":"alice","age":30}`, `{"age":31}`, `{"age":31,"name":"alice"}`},
+		{"delete field", `{"name":"alice","age":30}`, `{"age":null}`, `{"name":"alice"}`},
+		{"nested", `{"a":{"b":1,"c":2}}`, `{"a":{"c":null,"d":3}}`, `{"a":{"b":1,"d":3}}`},
+		{"replace array", `{"tags":["a","b"]}`, `{"tags":["c"]}`, `{"tags":["c"]}`},
+		{"empty original", ``, `{"a":1}`, `{"a":1}`},
+		{"non-object patch", `{"a":1}`, `"x"`, `"x"`},
+	}
+	for _, c := range cases {
+		got, err := MergePatch([]byte(c.original), []byte(c.patch))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if string(got) != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+	if _, err := MergePatch([]byte(`{`), []byte(`{}`)); err == nil {
+		t.Error("invalid original accepted")
+	}
+	if _, err := MergePatch([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Error("invalid patch accepted")
+	}
+}
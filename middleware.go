@@ -0,0 +1,444 @@
+package ming
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func BodyTimeout(d time.Duration, maxSize int64) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if err := readRequestBody(ctx, maxSize, time.Now().Add(d)); err != nil {
+				writeError(ctx, "request timeout", fasthttp.StatusRequestTimeout)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+func RequireContentType(types ...string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[mediaType(t)] = true
+	}
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if !allowed[mediaType(string(ctx.Request.Header.ContentType()))] {
+				writeError(ctx, "unsupported media type", fasthttp.StatusUnsupportedMediaType)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+const cacheSize = 1024
+
+type cachedResponse struct {
+	status      int
+	contentType []byte
+	vary        []string
+	body        []byte
+	expires     time.Time
+}
+
+func Cache(ttl time.Duration) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	cache := newLRU[*cachedResponse](cacheSize)
+	varies := newLRU[[]string](cacheSize)
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if !ctx.IsGet() || len(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization)) != 0 || len(ctx.Request.Header.Peek(fasthttp.HeaderCookie)) != 0 {
+				next(ctx)
+				return
+			}
+			base := string(ctx.Host()) + string(ctx.RequestURI())
+			vary, _ := varies.Get(base)
+			key := varyKey(ctx, base, vary)
+			if cached, ok := cache.Get(key); ok {
+				if time.Now().Before(cached.expires) {
+					ctx.SetStatusCode(cached.status)
+					ctx.SetContentTypeBytes(cached.contentType)
+					if len(cached.vary) != 0 {
+						ctx.Response.Header.Set(fasthttp.HeaderVary, strings.Join(cached.vary, ", "))
+					}
+					ctx.SetBody(cached.body)
+					return
+				}
+				cache.Delete(key)
+			}
+			next(ctx)
+			if ctx.Response.StatusCode() != fasthttp.StatusOK || ctx.Response.IsBodyStream() || !cacheable(ctx) {
+				return
+			}
+			vary, ok := responseVary(ctx)
+			if !ok {
+				return
+			}
+			varies.Set(base, vary)
+			cache.Set(varyKey(ctx, base, vary), &cachedResponse{
+				status:      ctx.Response.StatusCode(),
+				contentType: append([]byte(nil), ctx.Response.Header.ContentType()...),
+				vary:        vary,
+				body:        append([]byte(nil), ctx.Response.Body()...),
+				expires:     time.Now().Add(ttl),
+			})
+		}
+	}
+}
+
+func cacheable(ctx *fasthttp.RequestCtx) bool {
+	if len(ctx.Response.Header.Peek(fasthttp.HeaderSetCookie)) != 0 {
+		return false
+	}
+	for _, directive := range strings.Split(string(ctx.Response.Header.Peek(fasthttp.HeaderCacheControl)), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "private", "no-store":
+			return false
+		}
+	}
+	return true
+}
+
+func responseVary(ctx *fasthttp.RequestCtx) ([]string, bool) {
+	var names []string
+	for _, value := range ctx.Response.Header.PeekAll(fasthttp.HeaderVary) {
+		for _, name := range strings.Split(string(value), ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, true
+}
+
+func varyKey(ctx *fasthttp.RequestCtx, base string, vary []string) string {
+	key := base
+	for _, name := range vary {
+		key += "\n" + name + ":" + string(ctx.Request.Header.Peek(name))
+	}
+	return key
+}
+
+func AllowedHosts(hosts ...string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if !hostAllowed(string(ctx.Host()), hosts) {
+				writeError(ctx, "invalid host", fasthttp.StatusBadRequest)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+func hostAllowed(host string, hosts []string) bool {
+	host = strings.ToLower(host)
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if hostname == "" {
+		return false
+	}
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == host || allowed == hostname {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(hostname, allowed[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func MaxConcurrent(n int) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if n <= 0 {
+		panic("MaxConcurrent limit must be positive, got " + strconv.Itoa(n))
+	}
+	sem := make(chan struct{}, n)
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				next(ctx)
+			default:
+				writeError(ctx, "service unavailable", fasthttp.StatusServiceUnavailable)
+			}
+		}
+	}
+}
+
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int64
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	if int64(c.Len()+len(p)) > c.limit {
+		return 0, ErrBodyTooLarge
+	}
+	return c.Buffer.Write(p)
+}
+
+func DecompressRequest(maxSize int64) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			body := &cappedBuffer{limit: maxSize}
+			var err error
+			switch strings.ToLower(strings.TrimSpace(string(ctx.Request.Header.Peek(fasthttp.HeaderContentEncoding)))) {
+			case "", "identity":
+				next(ctx)
+				return
+			case "gzip":
+				_, err = fasthttp.WriteGunzip(body, ctx.Request.Body())
+			case "deflate":
+				_, err = fasthttp.WriteInflate(body, ctx.Request.Body())
+			case "br":
+				_, err = fasthttp.WriteUnbrotli(body, ctx.Request.Body())
+			default:
+				writeError(ctx, "unsupported content encoding", fasthttp.StatusUnsupportedMediaType)
+				return
+			}
+			if err == ErrBodyTooLarge {
+				writeError(ctx, "request entity too large", fasthttp.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				writeError(ctx, "malformed request body", fasthttp.StatusBadRequest)
+				return
+			}
+			ctx.Request.Header.Del(fasthttp.HeaderContentEncoding)
+			ctx.Request.SetBody(body.Bytes())
+			next(ctx)
+		}
+	}
+}
+
+type AuditRecord struct {
+	Method       string
+	Path         string
+	RequestBody  []byte
+	Status       int
+	ResponseBody []byte
+	Duration     time.Duration
+}
+
+func Audit(sink func(AuditRecord)) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+			record := AuditRecord{
+				Method:      string(ctx.Method()),
+				Path:        string(ctx.Path()),
+				RequestBody: append([]byte(nil), ctx.Request.Body()...),
+			}
+			next(ctx)
+			record.Duration = time.Since(start)
+			record.Status = ctx.Response.StatusCode()
+			if !ctx.Response.IsBodyStream() {
+				record.ResponseBody = append([]byte(nil), ctx.Response.Body()...)
+			}
+			sink(record)
+		}
+	}
+}
+
+func HeaderAllowlist(allowed ...string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	set := map[string]bool{
+		strings.ToLower(fasthttp.HeaderContentLength): true,
+		strings.ToLower(fasthttp.HeaderContentType):   true,
+	}
+	for _, h := range allowed {
+		set[strings.ToLower(h)] = true
+	}
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+			remove := []string{}
+			ctx.Response.Header.VisitAll(func(key, value []byte) {
+				if !set[strings.ToLower(string(key))] {
+					remove = append(remove, string(key))
+				}
+			})
+			for _, key := range remove {
+				ctx.Response.Header.Del(key)
+			}
+		}
+	}
+}
+
+func ServerHeader(name string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+			ctx.Response.Header.SetServer(name)
+		}
+	}
+}
+
+type pathWindow struct {
+	start time.Time
+	count int
+}
+
+func RateLimitPath(limits map[string]int) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	var mu sync.Mutex
+	windows := map[string]*pathWindow{}
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			path := string(ctx.Path())
+			limit, ok := limits[path]
+			if !ok {
+				next(ctx)
+				return
+			}
+			now := time.Now()
+			mu.Lock()
+			w := windows[path]
+			if w == nil || now.Sub(w.start) >= time.Second {
+				w = &pathWindow{start: now}
+				windows[path] = w
+			}
+			w.count++
+			exceeded := w.count > limit
+			mu.Unlock()
+			if exceeded {
+				ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, "1")
+				writeError(ctx, "too many requests", fasthttp.StatusTooManyRequests)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+func ResponseTime(headerName string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if headerName == "" {
+		headerName = "X-Response-Time"
+	}
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+			next(ctx)
+			elapsed := time.Since(start)
+			ctx.Response.Header.Set(headerName, fmt.Sprintf("%.3fms", float64(elapsed)/float64(time.Millisecond)))
+		}
+	}
+}
+
+func MaxHeaderSize(bytes int) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if len(ctx.Request.Header.Header()) > bytes {
+				writeError(ctx, "request header fields too large", fasthttp.StatusRequestHeaderFieldsTooLarge)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+func CacheControl(directive string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Set(fasthttp.HeaderCacheControl, directive)
+			next(ctx)
+		}
+	}
+}
+
+func JSONOnly() func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			switch requestMethod(ctx) {
+			case fasthttp.MethodPost, fasthttp.MethodPut, fasthttp.MethodPatch, fasthttp.MethodDelete:
+				t := mediaType(string(ctx.Request.Header.ContentType()))
+				if len(ctx.Request.Body()) != 0 && t != "application/json" && !strings.HasSuffix(t, "+json") {
+					writeError(ctx, "unsupported media type", fasthttp.StatusUnsupportedMediaType)
+					return
+				}
+			}
+			ctx.SetContentType("application/json")
+			next(ctx)
+		}
+	}
+}
+
+type slidingWindow struct {
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	limit     int
+	window    time.Duration
+	lastSweep time.Time
+}
+
+func (w *slidingWindow) allow(key string, now time.Time) (bool, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if now.Sub(w.lastSweep) >= w.window {
+		for k, times := range w.hits {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) >= w.window {
+				delete(w.hits, k)
+			}
+		}
+		w.lastSweep = now
+	}
+	recent := w.hits[key][:0]
+	for _, t := range w.hits[key] {
+		if now.Sub(t) < w.window {
+			recent = append(recent, t)
+		}
+	}
+	allowed := len(recent) < w.limit
+	if allowed {
+		recent = append(recent, now)
+	}
+	if len(recent) == 0 {
+		delete(w.hits, key)
+	} else {
+		w.hits[key] = recent
+	}
+	return allowed, w.limit - len(recent)
+}
+
+func QuotaPerKey(header string, limit int, window time.Duration) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	w := &slidingWindow{hits: map[string][]time.Time{}, limit: limit, window: window, lastSweep: time.Now()}
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			allowed, remaining := w.allow(string(ctx.Request.Header.Peek(header)), time.Now())
+			ctx.Response.Header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			ctx.Response.Header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			if !allowed {
+				writeError(ctx, "too many requests", fasthttp.StatusTooManyRequests)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+func MaxBodySize(limit int64) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if err := readRequestBody(ctx, limit, time.Time{}); err != nil {
+				writeError(ctx, "request entity too large", fasthttp.StatusRequestEntityTooLarge)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,504 @@
+package ming
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+type slowReader struct {
+	data  []byte
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	if len(s.data) == 0 {
+		return 0, io.EOF
+	}
+	p[0] = s.data[0]
+	s.data = s.data[1:]
+	return 1, nil
+}
+
+func newCtx(method, uri string) *fasthttp.RequestCtx {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(req, nil, nil)
+	return ctx
+}
+
+func TestBodyTimeout(t *testing.T) {
+	r := New()
+	r.Post("/", BodyTimeout(50*time.Millisecond, 16)(func(ctx *fasthttp.RequestCtx) {
+		ctx.Write(ctx.Request.Body())
+	}))
+
+	resp := r.ServeTest(fasthttp.MethodPost, "/", []byte("hello"))
+	if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "hello" {
+		t.Fatalf("normal body: %d %q", resp.StatusCode(), resp.Body())
+	}
+
+	resp = r.ServeTest(fasthttp.MethodPost, "/", []byte(strings.Repeat("x", 17)))
+	if resp.StatusCode() != fasthttp.StatusRequestTimeout {
+		t.Fatalf("oversized body status = %d, want 408", resp.StatusCode())
+	}
+
+	ctx := newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.SetBodyStream(&slowReader{data: []byte("0123456789"), delay: 20 * time.Millisecond}, -1)
+	r.Handler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusRequestTimeout {
+		t.Fatalf("slow body status = %d, want 408", ctx.Response.StatusCode())
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	r := New()
+	r.Post("/", MaxBodySize(8)(ok))
+
+	if resp := r.ServeTest(fasthttp.MethodPost, "/", []byte("small")); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("small body status = %d, want 200", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/", []byte("too large body")); resp.StatusCode() != fasthttp.StatusRequestEntityTooLarge {
+		t.Fatalf("large body status = %d, want 413", resp.StatusCode())
+	}
+
+	ctx := newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.SetBodyStream(strings.NewReader("too large chunked body"), -1)
+	r.Handler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusRequestEntityTooLarge {
+		t.Fatalf("chunked body status = %d, want 413", ctx.Response.StatusCode())
+	}
+}
+
+func TestQuotaPerKey(t *testing.T) {
+	r := New()
+	r.Get("/", QuotaPerKey("X-API-Key", 2, time.Minute)(ok))
+	status := func(key string) int {
+		ctx := newCtx(fasthttp.MethodGet, "/")
+		ctx.Request.Header.Set("X-API-Key", key)
+		r.Handler(ctx)
+		return ctx.Response.StatusCode()
+	}
+	if status("a") != fasthttp.StatusOK || status("a") != fasthttp.StatusOK {
+		t.Fatal("requests within quota rejected")
+	}
+	if got := status("a"); got != fasthttp.StatusTooManyRequests {
+		t.Fatalf("over quota status = %d, want 429", got)
+	}
+	if got := status("b"); got != fasthttp.StatusOK {
+		t.Fatalf("other key status = %d, want 200", got)
+	}
+}
+
+func TestSlidingWindowPrunesExpiredKeys(t *testing.T) {
+	now := time.Now()
+	w := &slidingWindow{hits: map[string][]time.Time{}, limit: 1, window: time.Second, lastSweep: now}
+	for _, key := range []string{"a", "b", "c"} {
+		w.allow(key, now)
+	}
+	if allowed, _ := w.allow("a", now); allowed {
+		t.Fatal("second hit within window allowed")
+	}
+	if allowed, _ := w.allow("d", now.Add(2*time.Second)); !allowed {
+		t.Fatal("hit after window rejected")
+	}
+	if len(w.hits) != 1 {
+		t.Fatalf("tracked keys = %d, want 1", len(w.hits))
+	}
+}
+
+func TestCacheKeysOnHostAndVary(t *testing.T) {
+	r := New()
+	r.Use(Cache(time.Minute))
+	r.GetAccept("/doc", map[string]fasthttp.RequestHandler{
+		"application/json": func(ctx *fasthttp.RequestCtx) { ctx.WriteString(`{"doc":1}`) },
+		"text/html":        func(ctx *fasthttp.RequestCtx) { ctx.WriteString("<p>doc</p>") },
+	})
+	r.Get("/host", func(ctx *fasthttp.RequestCtx) { ctx.Write(ctx.Host()) })
+
+	get := func(uri, header, value string) string {
+		ctx := newCtx(fasthttp.MethodGet, uri)
+		ctx.Request.Header.Set(header, value)
+		r.Handler(ctx)
+		return string(ctx.Response.Body())
+	}
+	for i := 0; i < 2; i++ {
+		if got := get("/doc", "Accept", "application/json"); got != `{"doc":1}` {
+			t.Fatalf("json representation = %q", got)
+		}
+		if got := get("/doc", "Accept", "text/html"); got != "<p>doc</p>" {
+			t.Fatalf("html representation = %q", got)
+		}
+		if got := get("http://a.test/host", "Accept", "*/*"); got != "a.test" {
+			t.Fatalf("host a = %q", got)
+		}
+		if got := get("http://b.test/host", "Accept", "*/*"); got != "b.test" {
+			t.Fatalf("host b = %q", got)
+		}
+	}
+}
+
+func TestDecompressRequest(t *testing.T) {
+	r := New()
+	r.Post("/", DecompressRequest(1<<10)(func(ctx *fasthttp.RequestCtx) {
+		ctx.Write(ctx.Request.Body())
+	}))
+	post := func(encoding string, body []byte) *fasthttp.Response {
+		ctx := newCtx(fasthttp.MethodPost, "/")
+		ctx.Request.Header.Set(fasthttp.HeaderContentEncoding, encoding)
+		ctx.Request.SetBody(body)
+		r.Handler(ctx)
+		return &ctx.Response
+	}
+
+	resp := post("gzip", fasthttp.AppendGzipBytes(nil, []byte("hello gzip")))
+	if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "hello gzip" {
+		t.Fatalf("gzip body: %d %q", resp.StatusCode(), resp.Body())
+	}
+	resp = post("deflate", fasthttp.AppendDeflateBytes(nil, []byte("hello deflate")))
+	if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "hello deflate" {
+		t.Fatalf("deflate body: %d %q", resp.StatusCode(), resp.Body())
+	}
+	if resp = post("identity", []byte("plain")); resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "plain" {
+		t.Fatalf("identity body: %d %q", resp.StatusCode(), resp.Body())
+	}
+	if resp = post("gzip", []byte("not gzip")); resp.StatusCode() != fasthttp.StatusBadRequest {
+		t.Fatalf("malformed gzip status = %d, want 400", resp.StatusCode())
+	}
+	if resp = post("gzip", fasthttp.AppendGzipBytes(nil, make([]byte, 1<<20))); resp.StatusCode() != fasthttp.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized gzip status = %d, want 413", resp.StatusCode())
+	}
+	if resp = post("compress", []byte("x")); resp.StatusCode() != fasthttp.StatusUnsupportedMediaType {
+		t.Fatalf("unknown encoding status = %d, want 415", resp.StatusCode())
+	}
+}
+
+func TestCache(t *testing.T) {
+	calls := 0
+	counting := func(ctx *fasthttp.RequestCtx) {
+		calls++
+		fmt.Fprintf(ctx, "%d %s", calls, ctx.Request.Header.Peek(fasthttp.HeaderAuthorization))
+	}
+	r := New()
+	r.Use(Cache(50 * time.Millisecond))
+	r.Get("/", counting)
+	r.Get("/cookie", func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set(fasthttp.HeaderSetCookie, "session=1")
+		counting(ctx)
+	})
+	r.Get("/private", func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "max-age=60, private")
+		counting(ctx)
+	})
+	get := func(uri, auth string) string {
+		ctx := newCtx(fasthttp.MethodGet, uri)
+		if auth != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAuthorization, auth)
+		}
+		r.Handler(ctx)
+		return string(ctx.Response.Body())
+	}
+
+	if first, second := get("/", ""), get("/", ""); first != "1 " || second != "1 " {
+		t.Fatalf("within ttl: %q then %q, want cached response", first, second)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := get("/", ""); got != "2 " {
+		t.Fatalf("after ttl: %q, want recomputed response", got)
+	}
+	if alice, bob := get("/", "alice"), get("/", "bob"); alice != "3 alice" || bob != "4 bob" {
+		t.Fatalf("authorized requests: %q, %q", alice, bob)
+	}
+	if first, second := get("/cookie", ""), get("/cookie", ""); first == second {
+		t.Fatalf("Set-Cookie response was cached: %q", second)
+	}
+	if first, second := get("/private", ""), get("/private", ""); first == second {
+		t.Fatalf("private response was cached: %q", second)
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	r := New()
+	r.Get("/slow", MaxConcurrent(2)(func(ctx *fasthttp.RequestCtx) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp := r.ServeTest(fasthttp.MethodGet, "/slow", nil); resp.StatusCode() != fasthttp.StatusOK {
+				t.Errorf("request within limit status = %d, want 200", resp.StatusCode())
+			}
+		}()
+	}
+	<-started
+	<-started
+	if resp := r.ServeTest(fasthttp.MethodGet, "/slow", nil); resp.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("request over limit status = %d, want 503", resp.StatusCode())
+	}
+	close(release)
+	wg.Wait()
+
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("MaxConcurrent(%d) did not panic", n)
+				}
+			}()
+			MaxConcurrent(n)
+		}()
+	}
+}
+
+func TestRequireContentType(t *testing.T) {
+	r := New()
+	r.Post("/", RequireContentType("application/json")(ok))
+	post := func(contentType string) int {
+		ctx := newCtx(fasthttp.MethodPost, "/")
+		ctx.Request.Header.SetContentType(contentType)
+		ctx.Request.SetBodyString("{}")
+		r.Handler(ctx)
+		return ctx.Response.StatusCode()
+	}
+	if got := post("application/json"); got != fasthttp.StatusOK {
+		t.Fatalf("allowed type status = %d, want 200", got)
+	}
+	if got := post("application/json; charset=utf-8"); got != fasthttp.StatusOK {
+		t.Fatalf("type with charset status = %d, want 200", got)
+	}
+	if got := post("text/plain"); got != fasthttp.StatusUnsupportedMediaType {
+		t.Fatalf("disallowed type status = %d, want 415", got)
+	}
+}
+
+func TestAllowedHosts(t *testing.T) {
+	r := New()
+	r.Use(AllowedHosts("example.com", "*.example.org"))
+	r.Get("/", ok)
+	cases := map[string]int{
+		"http://example.com/":      fasthttp.StatusOK,
+		"http://EXAMPLE.com:8080/": fasthttp.StatusOK,
+		"http://api.example.org/":  fasthttp.StatusOK,
+		"http://example.org/":      fasthttp.StatusBadRequest,
+		"http://evil.com/":         fasthttp.StatusBadRequest,
+		"http://example.com.evil/": fasthttp.StatusBadRequest,
+		"http://badexample.org/":   fasthttp.StatusBadRequest,
+	}
+	for uri, want := range cases {
+		if resp := r.ServeTest(fasthttp.MethodGet, uri, nil); resp.StatusCode() != want {
+			t.Errorf("%s status = %d, want %d", uri, resp.StatusCode(), want)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	var records []AuditRecord
+	r := New()
+	r.Use(Audit(func(record AuditRecord) { records = append(records, record) }))
+	r.Post("/users", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.WriteString(`{"id":1}`)
+	})
+
+	r.ServeTest(fasthttp.MethodPost, "/users", []byte(`{"name":"alice"}`))
+	if len(records) != 1 {
+		t.Fatalf("sink received %d records, want 1", len(records))
+	}
+	got := records[0]
+	if got.Method != fasthttp.MethodPost || got.Path != "/users" || got.Status != fasthttp.StatusCreated {
+		t.Fatalf("record = %+v", got)
+	}
+	if string(got.RequestBody) != `{"name":"alice"}` || string(got.ResponseBody) != `{"id":1}` {
+		t.Fatalf("bodies = %q, %q", got.RequestBody, got.ResponseBody)
+	}
+	if got.Duration < 0 {
+		t.Fatalf("duration = %v", got.Duration)
+	}
+}
+
+func TestHeaderAllowlist(t *testing.T) {
+	r := New()
+	r.Use(HeaderAllowlist("X-Request-Id"))
+	r.Get("/", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/plain")
+		ctx.Response.Header.Set("X-Request-Id", "abc")
+		ctx.Response.Header.Set("X-Internal-Trace", "secret")
+		ctx.Response.Header.Set("X-Powered-By", "ming")
+		ctx.WriteString("ok")
+	})
+
+	resp := r.ServeTest(fasthttp.MethodGet, "/", nil)
+	if got := string(resp.Header.Peek("X-Request-Id")); got != "abc" {
+		t.Fatalf("allowed header = %q", got)
+	}
+	if got := string(resp.Header.ContentType()); got != "text/plain" {
+		t.Fatalf("content type = %q", got)
+	}
+	for _, name := range []string{"X-Internal-Trace", "X-Powered-By"} {
+		if got := resp.Header.Peek(name); len(got) != 0 {
+			t.Fatalf("%s = %q, want removed", name, got)
+		}
+	}
+	if string(resp.Body()) != "ok" {
+		t.Fatalf("body = %q", resp.Body())
+	}
+}
+
+func TestServerHeader(t *testing.T) {
+	serverHeader := func(name string, opts ...RunOption) string {
+		r := New()
+		r.Use(ServerHeader(name))
+		r.Get("/", ok)
+		ln := fasthttputil.NewInmemoryListener()
+		defer ln.Close()
+		server := newServer(opts...)
+		server.Handler = r.Handler
+		go server.Serve(ln)
+
+		client := &fasthttp.Client{Dial: func(addr string) (net.Conn, error) { return ln.Dial() }}
+		req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		req.SetRequestURI("http://test/")
+		if err := client.Do(req, resp); err != nil {
+			t.Fatal(err)
+		}
+		return string(resp.Header.Peek(fasthttp.HeaderServer))
+	}
+
+	if got := serverHeader("ming"); got != "ming" {
+		t.Fatalf("Server = %q, want ming", got)
+	}
+	if got := serverHeader("", WithNoDefaultServerHeader()); got != "" {
+		t.Fatalf("Server = %q, want removed", got)
+	}
+}
+
+func TestRateLimitPath(t *testing.T) {
+	r := New()
+	r.Use(RateLimitPath(map[string]int{"/login": 1, "/search": 3}))
+	r.Post("/login", ok)
+	r.Get("/search", ok)
+	r.Get("/", ok)
+
+	statuses := func(method, uri string, n int) []int {
+		var got []int
+		for i := 0; i < n; i++ {
+			got = append(got, r.ServeTest(method, uri, nil).StatusCode())
+		}
+		return got
+	}
+	if got := fmt.Sprint(statuses(fasthttp.MethodPost, "/login", 2)); got != "[200 429]" {
+		t.Fatalf("/login statuses = %s, want [200 429]", got)
+	}
+	if got := fmt.Sprint(statuses(fasthttp.MethodGet, "/search", 4)); got != "[200 200 200 429]" {
+		t.Fatalf("/search statuses = %s, want [200 200 200 429]", got)
+	}
+	if got := fmt.Sprint(statuses(fasthttp.MethodGet, "/", 5)); got != "[200 200 200 200 200]" {
+		t.Fatalf("unlimited path statuses = %s", got)
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/login", nil); string(resp.Header.Peek(fasthttp.HeaderRetryAfter)) != "1" {
+		t.Fatalf("Retry-After = %q, want 1", resp.Header.Peek(fasthttp.HeaderRetryAfter))
+	}
+}
+
+func TestResponseTime(t *testing.T) {
+	r := New()
+	r.Get("/", ResponseTime("")(func(ctx *fasthttp.RequestCtx) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	r.Get("/custom", ResponseTime("Server-Timing")(ok))
+
+	value := string(r.ServeTest(fasthttp.MethodGet, "/", nil).Header.Peek("X-Response-Time"))
+	elapsed, err := time.ParseDuration(value)
+	if err != nil {
+		t.Fatalf("X-Response-Time %q: %v", value, err)
+	}
+	if elapsed < 5*time.Millisecond {
+		t.Fatalf("X-Response-Time = %v, want at least the handler duration", elapsed)
+	}
+	if value := r.ServeTest(fasthttp.MethodGet, "/custom", nil).Header.Peek("Server-Timing"); len(value) == 0 {
+		t.Fatal("custom header missing")
+	}
+}
+
+func TestMaxHeaderSize(t *testing.T) {
+	r := New()
+	r.Use(MaxHeaderSize(256))
+	r.Get("/", ok)
+	get := func(value string) int {
+		ctx := newCtx(fasthttp.MethodGet, "/")
+		ctx.Request.Header.Set("X-Data", value)
+		r.Handler(ctx)
+		return ctx.Response.StatusCode()
+	}
+	if got := get("small"); got != fasthttp.StatusOK {
+		t.Fatalf("small headers status = %d, want 200", got)
+	}
+	if got := get(strings.Repeat("x", 512)); got != fasthttp.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("large headers status = %d, want 431", got)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	r := New()
+	r.Get("/", CacheControl("public, max-age=3600")(ok))
+	r.Get("/override", CacheControl("public, max-age=3600")(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "no-store")
+	}))
+
+	if got := string(r.ServeTest(fasthttp.MethodGet, "/", nil).Header.Peek(fasthttp.HeaderCacheControl)); got != "public, max-age=3600" {
+		t.Fatalf("Cache-Control = %q", got)
+	}
+	if got := string(r.ServeTest(fasthttp.MethodGet, "/override", nil).Header.Peek(fasthttp.HeaderCacheControl)); got != "no-store" {
+		t.Fatalf("handler override Cache-Control = %q, want no-store", got)
+	}
+}
+
+func TestJSONOnly(t *testing.T) {
+	r := New()
+	r.Use(JSONOnly())
+	r.Get("/items", func(ctx *fasthttp.RequestCtx) { ctx.WriteString(`[]`) })
+	r.Post("/items", func(ctx *fasthttp.RequestCtx) { ctx.Write(ctx.Request.Body()) })
+	post := func(contentType, body string) *fasthttp.Response {
+		ctx := newCtx(fasthttp.MethodPost, "/items")
+		ctx.Request.Header.SetContentType(contentType)
+		ctx.Request.SetBodyString(body)
+		r.Handler(ctx)
+		return &ctx.Response
+	}
+
+	resp := post("application/json; charset=utf-8", `{"a":1}`)
+	if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != `{"a":1}` {
+		t.Fatalf("JSON POST: %d %q", resp.StatusCode(), resp.Body())
+	}
+	if got := string(resp.Header.ContentType()); got != "application/json" {
+		t.Fatalf("JSON POST content type = %q", got)
+	}
+	if resp := post("application/merge-patch+json", `{}`); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("+json POST status = %d, want 200", resp.StatusCode())
+	}
+	if resp := post("text/plain", "a=1"); resp.StatusCode() != fasthttp.StatusUnsupportedMediaType {
+		t.Fatalf("non-JSON POST status = %d, want 415", resp.StatusCode())
+	}
+	resp = r.ServeTest(fasthttp.MethodGet, "/items", nil)
+	if resp.StatusCode() != fasthttp.StatusOK || string(resp.Header.ContentType()) != "application/json" {
+		t.Fatalf("GET: %d %q", resp.StatusCode(), resp.Header.ContentType())
+	}
+}
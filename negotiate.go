@@ -0,0 +1,193 @@
+package ming
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type acceptSpec struct {
+	value string
+	q     float64
+}
+
+func parseAccept(header string) []acceptSpec {
+	specs := []acceptSpec{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(fields[0]))
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		specs = append(specs, acceptSpec{value: value, q: q})
+	}
+	sort.SliceStable(specs, func(i, j int) bool {
+		return specs[i].q > specs[j].q
+	})
+	return specs
+}
+
+func PreferredLanguage(ctx *fasthttp.RequestCtx, supported ...string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	specs := parseAccept(string(ctx.Request.Header.Peek(fasthttp.HeaderAcceptLanguage)))
+	excluded := map[string]bool{}
+	for _, spec := range specs {
+		if spec.q <= 0 {
+			excluded[spec.value] = true
+		}
+	}
+	allowed := func(lang string) bool {
+		return !excluded[strings.ToLower(lang)]
+	}
+	for _, spec := range specs {
+		if spec.q <= 0 {
+			continue
+		}
+		if spec.value == "*" {
+			for _, lang := range supported {
+				if allowed(lang) {
+					return lang
+				}
+			}
+			continue
+		}
+		for _, lang := range supported {
+			if strings.EqualFold(spec.value, lang) && allowed(lang) {
+				return lang
+			}
+		}
+		for _, lang := range supported {
+			l := strings.ToLower(lang)
+			if (strings.HasPrefix(l, spec.value+"-") || strings.HasPrefix(spec.value, l+"-")) && allowed(lang) {
+				return lang
+			}
+		}
+	}
+	return supported[0]
+}
+
+func negotiate(accept string, offers []string) string {
+	specs := parseAccept(accept)
+	if len(specs) == 0 {
+		specs = []acceptSpec{{value: "*/*", q: 1}}
+	}
+	best, bestQ, bestRank := "", 0.0, len(specs)
+	for _, offer := range offers {
+		q, rank := offerQuality(specs, strings.ToLower(offer))
+		if q > bestQ || (q > 0 && q == bestQ && rank < bestRank) {
+			best, bestQ, bestRank = offer, q, rank
+		}
+	}
+	return best
+}
+
+func offerQuality(specs []acceptSpec, offer string) (float64, int) {
+	q, rank, specificity := 0.0, len(specs), -1
+	for i, spec := range specs {
+		level := -1
+		switch true {
+		case spec.value == offer:
+			level = 2
+		case spec.value == "*/*":
+			level = 0
+		case strings.HasSuffix(spec.value, "/*") && strings.HasPrefix(offer, spec.value[:len(spec.value)-1]):
+			level = 1
+		}
+		if level > specificity {
+			q, rank, specificity = spec.q, i, level
+		}
+	}
+	return q, rank
+}
+
+func Negotiate(ctx *fasthttp.RequestCtx, offers ...string) string {
+	return negotiate(string(ctx.Request.Header.Peek(fasthttp.HeaderAccept)), offers)
+}
+
+func (r *Router) GetAccept(path string, handlers map[string]fasthttp.RequestHandler) {
+	offers := []string{}
+	for mediaType := range handlers {
+		if mediaType != "*/*" {
+			offers = append(offers, mediaType)
+		}
+	}
+	sort.Strings(offers)
+	r.Get(path, func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderAccept)
+		fallback := handlers["*/*"]
+		if fallback != nil && !specificAccept(string(ctx.Request.Header.Peek(fasthttp.HeaderAccept))) {
+			fallback(ctx)
+		} else if offer := Negotiate(ctx, offers...); offer != "" {
+			handlers[offer](ctx)
+		} else if fallback != nil {
+			fallback(ctx)
+		} else if r.NotAcceptable != nil {
+			r.NotAcceptable(ctx)
+		} else {
+			writeError(ctx, "not acceptable", fasthttp.StatusNotAcceptable)
+		}
+	})
+}
+
+func specificAccept(accept string) bool {
+	for _, spec := range parseAccept(accept) {
+		if spec.value != "*/*" {
+			return true
+		}
+	}
+	return false
+}
+
+var supportedEncodings = map[string]bool{
+	"gzip":     true,
+	"deflate":  true,
+	"br":       true,
+	"identity": true,
+	"*":        true,
+}
+
+func NormalizeAcceptEncoding() func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			header := ctx.Request.Header.Peek(fasthttp.HeaderAcceptEncoding)
+			if len(header) == 0 {
+				next(ctx)
+				return
+			}
+			parts := []string{}
+			seen := map[string]bool{}
+			for _, spec := range parseAccept(string(header)) {
+				if !supportedEncodings[spec.value] || seen[spec.value] {
+					continue
+				}
+				seen[spec.value] = true
+				if spec.q <= 0 {
+					parts = append(parts, spec.value+";q=0")
+				} else if spec.q < 1 {
+					parts = append(parts, spec.value+";q="+strconv.FormatFloat(spec.q, 'g', 3, 64))
+				} else {
+					parts = append(parts, spec.value)
+				}
+			}
+			if len(parts) == 0 {
+				ctx.Request.Header.Del(fasthttp.HeaderAcceptEncoding)
+			} else {
+				ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, strings.Join(parts, ", "))
+			}
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package ming
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "text/html"}
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"text/html", "text/html"},
+		{"text/*;q=0.5, application/json;q=0.4", "text/html"},
+		{"application/json;q=0, */*", "text/html"},
+		{"text/html;q=0, application/*;q=0", ""},
+		{"image/png", ""},
+	}
+	for _, c := range cases {
+		if got := negotiate(c.accept, offers); got != c.want {
+			t.Errorf("negotiate(%q) = %q, want %q", c.accept, got, c.want)
+		}
+	}
+}
+
+func TestGetAcceptNotAcceptable(t *testing.T) {
+	r := New()
+	r.GetAccept("/doc", map[string]fasthttp.RequestHandler{
+		"application/json": ok,
+	})
+	get := func(accept string) *fasthttp.Response {
+		ctx := newCtx(fasthttp.MethodGet, "/doc")
+		ctx.Request.Header.Set(fasthttp.HeaderAccept, accept)
+		r.Handler(ctx)
+		return &ctx.Response
+	}
+	if resp := get("image/png"); resp.StatusCode() != fasthttp.StatusNotAcceptable {
+		t.Fatalf("unmatched accept status = %d, want 406", resp.StatusCode())
+	}
+	if resp := get("application/json;q=0, */*"); resp.StatusCode() != fasthttp.StatusNotAcceptable {
+		t.Fatalf("excluded offer status = %d, want 406", resp.StatusCode())
+	}
+
+	r.NotAcceptable = func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusNotAcceptable)
+		ctx.WriteString("custom")
+	}
+	if resp := get("image/png"); resp.StatusCode() != fasthttp.StatusNotAcceptable || string(resp.Body()) != "custom" {
+		t.Fatalf("custom 406: %d %q", resp.StatusCode(), resp.Body())
+	}
+}
+
+func TestGetAccept(t *testing.T) {
+	handler := func(name string) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) { ctx.WriteString(name) }
+	}
+	r := New()
+	r.GetAccept("/doc", map[string]fasthttp.RequestHandler{
+		"application/json": handler("json"),
+		"text/html":        handler("html"),
+		"*/*":              handler("default"),
+	})
+	cases := map[string]string{
+		"application/json":                  "json",
+		"text/html":                         "html",
+		"text/html;q=0.5, application/json": "json",
+		"text/*":                            "html",
+		"*/*":                               "default",
+		"":                                  "default",
+		"image/png":                         "default",
+	}
+	for accept, want := range cases {
+		ctx := newCtx(fasthttp.MethodGet, "/doc")
+		if accept != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAccept, accept)
+		}
+		r.Handler(ctx)
+		if got := string(ctx.Response.Body()); got != want {
+			t.Errorf("Accept %q served %q, want %q", accept, got, want)
+		}
+		if got := string(ctx.Response.Header.Peek(fasthttp.HeaderVary)); got != fasthttp.HeaderAccept {
+			t.Errorf("Accept %q Vary = %q", accept, got)
+		}
+	}
+}
+
+func TestNormalizeAcceptEncoding(t *testing.T) {
+	r := New()
+	r.Use(NormalizeAcceptEncoding())
+	r.Get("/", func(ctx *fasthttp.RequestCtx) {
+		ctx.Write(ctx.Request.Header.Peek(fasthttp.HeaderAcceptEncoding))
+	})
+	cases := map[string]string{
+		" GZIP ;q=0.8,, br, gzip, compress, identity;q=0": "br, gzip, identity;q=0",
+		"*;q=0.1, deflate": "deflate, *;q=0.1",
+		"compress":         "",
+		"":                 "",
+	}
+	for header, want := range cases {
+		ctx := newCtx(fasthttp.MethodGet, "/")
+		if header != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, header)
+		}
+		r.Handler(ctx)
+		if got := string(ctx.Response.Body()); got != want {
+			t.Errorf("%q normalized to %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{"fr;q=0.9, en;q=0.8", "fr"},
+		{"", "en"},
+		{"de", "en"},
+		{"en-US, fr;q=0.5", "en"},
+		{"*, en;q=0", "fr"},
+	}
+	for _, c := range cases {
+		ctx := newCtx(fasthttp.MethodGet, "/")
+		if c.header != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAcceptLanguage, c.header)
+		}
+		if got := PreferredLanguage(ctx, "en", "fr"); got != c.want {
+			t.Errorf("PreferredLanguage(%q) = %q, want %q", c.header, got, c.want)
+		}
+	}
+}
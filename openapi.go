@@ -0,0 +1,99 @@
+package ming
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+}
+
+func (r *Router) HandleDoc(method, path string, doc RouteDoc, handler fasthttp.RequestHandler) {
+	r.addNode(&Node{
+		method:  method,
+		path:    path,
+		handler: handler,
+		doc:     &doc,
+	})
+}
+
+func (r *Router) GetDoc(path string, doc RouteDoc, handler fasthttp.RequestHandler) {
+	r.HandleDoc(fasthttp.MethodGet, path, doc, handler)
+}
+
+func (r *Router) PostDoc(path string, doc RouteDoc, handler fasthttp.RequestHandler) {
+	r.HandleDoc(fasthttp.MethodPost, path, doc, handler)
+}
+
+func (r *Router) PutDoc(path string, doc RouteDoc, handler fasthttp.RequestHandler) {
+	r.HandleDoc(fasthttp.MethodPut, path, doc, handler)
+}
+
+func (r *Router) PatchDoc(path string, doc RouteDoc, handler fasthttp.RequestHandler) {
+	r.HandleDoc(fasthttp.MethodPatch, path, doc, handler)
+}
+
+func (r *Router) DeleteDoc(path string, doc RouteDoc, handler fasthttp.RequestHandler) {
+	r.HandleDoc(fasthttp.MethodDelete, path, doc, handler)
+}
+
+var openAPIMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+func (r *Router) OpenAPISpec() []byte {
+	paths := map[string]map[string]interface{}{}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, v := range *r.trees {
+		methods := []string{strings.ToLower(v.method)}
+		if v.method == "ALL" {
+			methods = []string{}
+			for _, m := range standardMethods {
+				methods = append(methods, strings.ToLower(m))
+			}
+		}
+		for _, m := range methods {
+			if !openAPIMethods[m] {
+				continue
+			}
+			operation := map[string]interface{}{
+				"responses": map[string]interface{}{
+					"default": map[string]string{"description": "response"},
+				},
+			}
+			if v.doc != nil {
+				if v.doc.Summary != "" {
+					operation["summary"] = v.doc.Summary
+				}
+				if v.doc.Description != "" {
+					operation["description"] = v.doc.Description
+				}
+				if len(v.doc.Tags) != 0 {
+					operation["tags"] = v.doc.Tags
+				}
+			}
+			if paths[v.path] == nil {
+				paths[v.path] = map[string]interface{}{}
+			}
+			if _, ok := paths[v.path][m]; !ok {
+				paths[v.path][m] = operation
+			}
+		}
+	}
+	spec, _ := json.Marshal(map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   "ming",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+	})
+	return spec
+}
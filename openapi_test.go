@@ -0,0 +1,38 @@
+package ming
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	r := New()
+	r.GetDoc("/users", RouteDoc{Summary: "List users", Tags: []string{"users"}}, ok)
+	r.PostDoc("/users", RouteDoc{Description: "Create a user"}, ok)
+	r.Get("/health", ok)
+
+	var spec struct {
+		OpenAPI string
+		Paths   map[string]map[string]struct {
+			Summary     string
+			Description string
+			Tags        []string
+		}
+	}
+	if err := json.Unmarshal(r.OpenAPISpec(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.OpenAPI != "3.0.3" {
+		t.Fatalf("openapi = %q", spec.OpenAPI)
+	}
+	list, create := spec.Paths["/users"]["get"], spec.Paths["/users"]["post"]
+	if list.Summary != "List users" || len(list.Tags) != 1 || list.Tags[0] != "users" {
+		t.Fatalf("GET /users = %+v", list)
+	}
+	if create.Description != "Create a user" {
+		t.Fatalf("POST /users = %+v", create)
+	}
+	if _, ok := spec.Paths["/health"]["get"]; !ok {
+		t.Fatalf("undocumented route missing: %v", spec.Paths)
+	}
+}
@@ -0,0 +1,21 @@
+package ming
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+func (r *Router) MountPprof(prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	r.Get(prefix+"/", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Index))
+	r.Get(prefix+"/cmdline", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Cmdline))
+	r.Get(prefix+"/profile", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Profile))
+	r.Get(prefix+"/symbol", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Symbol))
+	r.Post(prefix+"/symbol", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Symbol))
+	r.Get(prefix+"/trace", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Trace))
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		r.Get(prefix+"/"+name, fasthttpadaptor.NewFastHTTPHandler(pprof.Handler(name)))
+	}
+}
@@ -0,0 +1,22 @@
+package ming
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestMountPprof(t *testing.T) {
+	r := New()
+	r.MountPprof("/debug/pprof/")
+
+	resp := r.ServeTest(fasthttp.MethodGet, "/debug/pprof/", nil)
+	if resp.StatusCode() != fasthttp.StatusOK || !strings.Contains(string(resp.Body()), "goroutine") {
+		t.Fatalf("index: %d %q", resp.StatusCode(), resp.Body())
+	}
+	resp = r.ServeTest(fasthttp.MethodGet, "/debug/pprof/goroutine?debug=1", nil)
+	if resp.StatusCode() != fasthttp.StatusOK || !strings.Contains(string(resp.Body()), "goroutine profile") {
+		t.Fatalf("goroutine profile: %d %q", resp.StatusCode(), resp.Body())
+	}
+}
@@ -0,0 +1,91 @@
+package ming
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const proxyTimeout = 30 * time.Second
+
+type proxyRoute struct {
+	prefix  string
+	handler fasthttp.RequestHandler
+}
+
+func (r *Router) Proxy(prefix, targetBaseURL string) {
+	client := &fasthttp.Client{
+		ReadTimeout:            proxyTimeout,
+		WriteTimeout:           proxyTimeout,
+		MaxConnWaitTimeout:     proxyTimeout,
+		MaxIdleConnDuration:    proxyTimeout,
+		DisablePathNormalizing: true,
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	target := strings.TrimSuffix(targetBaseURL, "/")
+	handler := func(ctx *fasthttp.RequestCtx) {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		path, ok := proxyPath(string(ctx.URI().PathOriginal()), prefix)
+		if !ok {
+			writeError(ctx, "bad request", fasthttp.StatusBadRequest)
+			return
+		}
+		ctx.Request.CopyTo(req)
+		uri := target + path
+		if query := ctx.URI().QueryString(); len(query) != 0 {
+			uri += "?" + string(query)
+		}
+		req.SetRequestURI(uri)
+		req.Header.SetHostBytes(req.URI().Host())
+		req.Header.Del(fasthttp.HeaderConnection)
+		if err := client.DoTimeout(req, resp, proxyTimeout); err != nil {
+			writeError(ctx, "bad gateway", fasthttp.StatusBadGateway)
+			return
+		}
+		resp.Header.Del(fasthttp.HeaderConnection)
+		resp.CopyTo(&ctx.Response)
+	}
+	r.mu.Lock()
+	r.proxies = append(r.proxies, proxyRoute{prefix: prefix, handler: handler})
+	r.mu.Unlock()
+}
+
+func proxyPath(raw, prefix string) (string, bool) {
+	if raw != prefix && !strings.HasPrefix(raw, prefix+"/") {
+		return "", false
+	}
+	path := strings.TrimPrefix(raw, prefix)
+	decoded, err := url.PathUnescape(path)
+	if err != nil {
+		return "", false
+	}
+	for _, segment := range strings.Split(strings.ReplaceAll(decoded, "\\", "/"), "/") {
+		if segment == "." || segment == ".." {
+			return "", false
+		}
+	}
+	return path, true
+}
+
+func (r *Router) findProxy(path string) fasthttp.RequestHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var match *proxyRoute
+	for i, p := range r.proxies {
+		if path != p.prefix && !strings.HasPrefix(path, p.prefix+"/") {
+			continue
+		}
+		if match == nil || len(p.prefix) > len(match.prefix) {
+			match = &r.proxies[i]
+		}
+	}
+	if match == nil {
+		return nil
+	}
+	return match.handler
+}
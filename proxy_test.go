@@ -0,0 +1,47 @@
+package ming
+
+import (
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestProxy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("X-Upstream-Method", string(ctx.Method()))
+		ctx.Write(ctx.RequestURI())
+	})
+
+	r := New()
+	r.Proxy("/api", "http://"+ln.Addr().String()+"/v1")
+	r.SetMethodNotFound(fasthttp.MethodPost, ok)
+	r.Static(t.TempDir(), false)
+
+	resp := r.ServeTest(fasthttp.MethodPost, "/api/users%2F1?x=1", []byte("{}"))
+	if resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode())
+	}
+	if got := string(resp.Body()); got != "/v1/users%2F1?x=1" {
+		t.Fatalf("forwarded uri = %q", got)
+	}
+	if got := string(resp.Header.Peek("X-Upstream-Method")); got != fasthttp.MethodPost {
+		t.Fatalf("forwarded method = %q", got)
+	}
+
+	for _, uri := range []string{"/../../api/x", "/api/x/../y", "/api/x/%2e%2e/y", "/api/a%2F..%2Fb"} {
+		if resp := r.ServeTest(fasthttp.MethodGet, uri, nil); resp.StatusCode() != fasthttp.StatusBadRequest {
+			t.Fatalf("%s status = %d, want 400 (body %q)", uri, resp.StatusCode(), resp.Body())
+		}
+	}
+
+	r.Proxy("/down", "http://127.0.0.1:1")
+	if resp := r.ServeTest(fasthttp.MethodGet, "/down/x", nil); resp.StatusCode() != fasthttp.StatusBadGateway {
+		t.Fatalf("unreachable upstream status = %d, want 502", resp.StatusCode())
+	}
+}
@@ -0,0 +1,147 @@
+package ming
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+type jsonArrayWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (a *jsonArrayWriter) Write(p []byte) (int, error) {
+	if a.count > 0 {
+		if _, err := a.w.Write([]byte(",")); err != nil {
+			return 0, err
+		}
+	}
+	a.count++
+	return a.w.Write(p)
+}
+
+func StreamJSONArray(ctx *fasthttp.RequestCtx, produce func(enc *json.Encoder) error) {
+	ctx.SetContentType("application/json")
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		w.WriteString("[")
+		if err := produce(json.NewEncoder(&jsonArrayWriter{w: w})); err != nil {
+			return
+		}
+		w.WriteString("]")
+	})
+}
+
+func NotModifiedSince(ctx *fasthttp.RequestCtx, modtime time.Time) bool {
+	modtime = modtime.UTC().Truncate(time.Second)
+	ctx.Response.Header.SetLastModified(modtime)
+	since, err := fasthttp.ParseHTTPDate(ctx.Request.Header.Peek(fasthttp.HeaderIfModifiedSince))
+	if err != nil || modtime.After(since) {
+		return false
+	}
+	ctx.NotModified()
+	ctx.Response.Header.SetLastModified(modtime)
+	return true
+}
+
+var ErrStreamIdle = errors.New("stream closed after idle timeout")
+
+type StreamWriter struct {
+	w      *bufio.Writer
+	conn   io.Closer
+	mu     sync.Mutex
+	last   int64
+	closed int32
+	done   chan struct{}
+}
+
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return 0, ErrStreamIdle
+	}
+	atomic.StoreInt64(&s.last, time.Now().UnixNano())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func (s *StreamWriter) Flush() error {
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return ErrStreamIdle
+	}
+	atomic.StoreInt64(&s.last, time.Now().UnixNano())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Flush()
+}
+
+func (s *StreamWriter) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *StreamWriter) expire(idle time.Duration) bool {
+	if time.Since(time.Unix(0, atomic.LoadInt64(&s.last))) < idle {
+		return false
+	}
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return true
+	}
+	close(s.done)
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	return true
+}
+
+func StreamWithIdleTimeout(ctx *fasthttp.RequestCtx, idle time.Duration, stream func(sw *StreamWriter)) {
+	ctx.SetConnectionClose()
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		sw := &StreamWriter{w: w, conn: ctx.Conn(), last: time.Now().UnixNano(), done: make(chan struct{})}
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			interval := idle / 4
+			if interval <= 0 {
+				interval = time.Millisecond
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					if sw.expire(idle) {
+						return
+					}
+				}
+			}
+		}()
+		stream(sw)
+	})
+}
+
+func HTML(ctx *fasthttp.RequestCtx, status int, html string) {
+	ctx.SetContentType("text/html; charset=utf-8")
+	ctx.SetStatusCode(status)
+	ctx.SetBodyString(html)
+}
+
+func Template(ctx *fasthttp.RequestCtx, status int, t *template.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	ctx.SetContentType("text/html; charset=utf-8")
+	ctx.SetStatusCode(status)
+	ctx.SetBody(buf.Bytes())
+	return nil
+}
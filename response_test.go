@@ -0,0 +1,118 @@
+package ming
+
+import (
+	"bufio"
+	"encoding/json"
+	"html/template"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestStreamWithIdleTimeoutClosesConnection(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+		StreamWithIdleTimeout(ctx, 20*time.Millisecond, func(sw *StreamWriter) {
+			sw.Write([]byte("first"))
+			sw.Flush()
+			<-release
+		})
+	})
+
+	conn, err := ln.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(bufio.NewReader(conn))
+		done <- err
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("connection still open after idle timeout")
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tpl := template.Must(template.New("page").Parse(`<h1>{{.Title}}</h1>`))
+	r := New()
+	r.Get("/", func(ctx *fasthttp.RequestCtx) {
+		if err := Template(ctx, fasthttp.StatusOK, tpl, map[string]string{"Title": "<Ming>"}); err != nil {
+			t.Error(err)
+		}
+	})
+	resp := r.ServeTest(fasthttp.MethodGet, "/", nil)
+	if got := string(resp.Body()); got != "<h1>&lt;Ming&gt;</h1>" {
+		t.Fatalf("body = %q", got)
+	}
+	if got := string(resp.Header.ContentType()); got != "text/html; charset=utf-8" {
+		t.Fatalf("content type = %q", got)
+	}
+}
+
+func TestStreamJSONArray(t *testing.T) {
+	r := New()
+	r.Get("/", func(ctx *fasthttp.RequestCtx) {
+		StreamJSONArray(ctx, func(enc *json.Encoder) error {
+			for i := 1; i <= 3; i++ {
+				if err := enc.Encode(map[string]int{"id": i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	resp := r.ServeTest(fasthttp.MethodGet, "/", nil)
+	var items []map[string]int
+	if err := json.Unmarshal(resp.Body(), &items); err != nil {
+		t.Fatalf("invalid JSON %q: %v", resp.Body(), err)
+	}
+	if len(items) != 3 || items[0]["id"] != 1 || items[2]["id"] != 3 {
+		t.Fatalf("items = %v", items)
+	}
+}
+
+func TestNotModifiedSince(t *testing.T) {
+	modtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := New()
+	r.Get("/", func(ctx *fasthttp.RequestCtx) {
+		if NotModifiedSince(ctx, modtime) {
+			return
+		}
+		ctx.WriteString("content")
+	})
+	get := func(since string) *fasthttp.Response {
+		ctx := newCtx(fasthttp.MethodGet, "/")
+		if since != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderIfModifiedSince, since)
+		}
+		r.Handler(ctx)
+		return &ctx.Response
+	}
+
+	for _, since := range []string{"", "Mon, 01 Jan 2024 00:00:00 GMT"} {
+		resp := get(since)
+		if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "content" {
+			t.Fatalf("modified since %q: %d %q", since, resp.StatusCode(), resp.Body())
+		}
+		if got := string(resp.Header.Peek(fasthttp.HeaderLastModified)); got != "Tue, 02 Jan 2024 03:04:05 GMT" {
+			t.Fatalf("Last-Modified = %q", got)
+		}
+	}
+	for _, since := range []string{"Tue, 02 Jan 2024 03:04:05 GMT", "Wed, 03 Jan 2024 00:00:00 GMT"} {
+		resp := get(since)
+		if resp.StatusCode() != fasthttp.StatusNotModified || len(resp.Body()) != 0 {
+			t.Fatalf("unmodified since %q: %d %q", since, resp.StatusCode(), resp.Body())
+		}
+	}
+}
@@ -1,21 +1,51 @@
 package ming
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"mime/multipart"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/valyala/fasthttp"
 )
 
 var (
 	DefaultContentType = []byte("text/plain; charset=utf-8")
+	ErrBodyTooLarge    = errors.New("request body too large")
+	ErrNotJSONArray    = errors.New("request body is not a json array")
+	ErrBodyTimeout     = errors.New("request body read timeout")
 )
 
 type Router struct {
-	trees            *Tree
-	PanicHandler     func(*fasthttp.RequestCtx, interface{})
-	NotFound         fasthttp.RequestHandler
-	MethodNotAllowed fasthttp.RequestHandler
+	requests               uint64
+	statusClasses          [6]uint64
+	mu                     sync.RWMutex
+	trees                  *Tree
+	matchCache             *lru[*Tree]
+	PanicHandler           func(*fasthttp.RequestCtx, interface{})
+	ErrorHandler           func(*fasthttp.RequestCtx, error)
+	NotFound               fasthttp.RequestHandler
+	MethodNotAllowed       fasthttp.RequestHandler
+	NotAcceptable          fasthttp.RequestHandler
+	MaxURILength           int
+	MaintenanceAllow       []string
+	VersionBase            string
+	AutoNoContent          bool
+	DisableDefaultRecovery bool
+	Debug                  bool
+	maintenance            int32
+	retryAfter             int32
+	methodNotFound         map[string]fasthttp.RequestHandler
+	errorHandlers          map[int]fasthttp.RequestHandler
+	proxies                []proxyRoute
+	middlewares            []func(fasthttp.RequestHandler) fasthttp.RequestHandler
+	chain                  fasthttp.RequestHandler
 }
 
 func New() *Router {
@@ -25,6 +55,99 @@ func New() *Router {
 	}
 }
 
+func (r *Router) Clone() *Router {
+	r.mu.RLock()
+	trees := r.trees.Clone()
+	proxies := append([]proxyRoute(nil), r.proxies...)
+	cacheSize := 0
+	if r.matchCache != nil {
+		cacheSize = r.matchCache.capacity
+	}
+	r.mu.RUnlock()
+	clone := &Router{
+		trees:                  trees,
+		proxies:                proxies,
+		PanicHandler:           r.PanicHandler,
+		ErrorHandler:           r.ErrorHandler,
+		NotFound:               r.NotFound,
+		MethodNotAllowed:       r.MethodNotAllowed,
+		NotAcceptable:          r.NotAcceptable,
+		MaxURILength:           r.MaxURILength,
+		MaintenanceAllow:       append([]string(nil), r.MaintenanceAllow...),
+		VersionBase:            r.VersionBase,
+		AutoNoContent:          r.AutoNoContent,
+		DisableDefaultRecovery: r.DisableDefaultRecovery,
+		Debug:                  r.Debug,
+		maintenance:            atomic.LoadInt32(&r.maintenance),
+		retryAfter:             atomic.LoadInt32(&r.retryAfter),
+	}
+	groups := map[*RouteGroup]*RouteGroup{}
+	for _, n := range *trees {
+		if n.handlerE == nil {
+			continue
+		}
+		if n.group == nil {
+			n.handler = wrapE(n.handlerE, clone.handleError)
+		} else {
+			n.group = n.group.rebind(clone, groups)
+			n.handler = n.group.wrap(wrapE(n.handlerE, n.group.handleError))
+		}
+	}
+	if cacheSize > 0 {
+		clone.EnableMatchCache(cacheSize)
+	}
+	for method, handler := range r.methodNotFound {
+		clone.SetMethodNotFound(method, handler)
+	}
+	for status, handler := range r.errorHandlers {
+		clone.SetErrorHandler(status, handler)
+	}
+	if len(r.middlewares) != 0 {
+		clone.Use(r.middlewares...)
+	}
+	return clone
+}
+
+func (r *Router) SetMaintenance(on bool, retryAfter int) {
+	atomic.StoreInt32(&r.retryAfter, int32(retryAfter))
+	if on {
+		atomic.StoreInt32(&r.maintenance, 1)
+	} else {
+		atomic.StoreInt32(&r.maintenance, 0)
+	}
+}
+
+func (r *Router) inMaintenance(ctx *fasthttp.RequestCtx) bool {
+	if atomic.LoadInt32(&r.maintenance) == 0 {
+		return false
+	}
+	path := string(ctx.Path())
+	for _, allowed := range r.MaintenanceAllow {
+		if path == allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Router) RequestCount() uint64 {
+	return atomic.LoadUint64(&r.requests)
+}
+
+func (r *Router) StatusClassCount(class int) uint64 {
+	if class < 1 || class > 5 {
+		return 0
+	}
+	return atomic.LoadUint64(&r.statusClasses[class])
+}
+
+func (r *Router) count(ctx *fasthttp.RequestCtx) {
+	atomic.AddUint64(&r.requests, 1)
+	if class := ctx.Response.StatusCode() / 100; class >= 1 && class <= 5 {
+		atomic.AddUint64(&r.statusClasses[class], 1)
+	}
+}
+
 type HostSwitch map[string]fasthttp.RequestHandler
 
 func (hs HostSwitch) CheckHost(ctx *fasthttp.RequestCtx) {
@@ -36,16 +159,82 @@ func (hs HostSwitch) CheckHost(ctx *fasthttp.RequestCtx) {
 }
 
 func (r *Router) Run(addr string) {
+	r.RunWithServer(&fasthttp.Server{}, addr)
+}
+
+func (r *Router) RunWithServer(server *fasthttp.Server, addr string) {
 	if strings.HasPrefix(addr, ":") {
-		log.Fatal(fasthttp.ListenAndServe(addr, r.Handler))
+		server.Handler = r.Handler
+		log.Fatal(server.ListenAndServe(addr))
 	} else {
 		port := ":" + strings.Split(addr, ":")[1]
 		hs := make(HostSwitch)
 		hs[addr] = r.Handler
-		log.Fatal(fasthttp.ListenAndServe(port, hs.CheckHost))
+		server.Handler = hs.CheckHost
+		log.Fatal(server.ListenAndServe(port))
+	}
+}
+
+type RunOption func(*fasthttp.Server)
+
+func WithConcurrency(n int) RunOption {
+	return func(s *fasthttp.Server) {
+		s.Concurrency = n
+	}
+}
+
+func WithDisableKeepalive() RunOption {
+	return func(s *fasthttp.Server) {
+		s.DisableKeepalive = true
+	}
+}
+
+func WithReadTimeout(d time.Duration) RunOption {
+	return func(s *fasthttp.Server) {
+		s.ReadTimeout = d
+	}
+}
+
+func WithWriteTimeout(d time.Duration) RunOption {
+	return func(s *fasthttp.Server) {
+		s.WriteTimeout = d
+	}
+}
+
+func WithIdleTimeout(d time.Duration) RunOption {
+	return func(s *fasthttp.Server) {
+		s.IdleTimeout = d
+	}
+}
+
+func WithMaxRequestBodySize(n int) RunOption {
+	return func(s *fasthttp.Server) {
+		s.MaxRequestBodySize = n
 	}
 }
 
+func WithNoDefaultServerHeader() RunOption {
+	return func(s *fasthttp.Server) {
+		s.NoDefaultServerHeader = true
+	}
+}
+
+func newServer(opts ...RunOption) *fasthttp.Server {
+	server := &fasthttp.Server{}
+	for _, opt := range opts {
+		opt(server)
+	}
+	return server
+}
+
+func (r *Router) RunWithOptions(addr string, opts ...RunOption) {
+	r.RunWithServer(newServer(opts...), addr)
+}
+
+func (r *Router) FastHTTPHandler() fasthttp.RequestHandler {
+	return r.Handler
+}
+
 func Query(ctx *fasthttp.RequestCtx, str string) []byte {
 	return ctx.QueryArgs().Peek(str)
 }
@@ -54,12 +243,127 @@ func SetHeader(ctx *fasthttp.RequestCtx, key string, value string) {
 	ctx.Response.Header.Set(key, value)
 }
 
+func SetHeaders(ctx *fasthttp.RequestCtx, headers map[string]string) {
+	for key, value := range headers {
+		ctx.Response.Header.Set(key, value)
+	}
+}
+
+func AddHeader(ctx *fasthttp.RequestCtx, key string, value string) {
+	ctx.Response.Header.Add(key, value)
+}
+
 func Body(ctx *fasthttp.RequestCtx) []byte {
 	return ctx.Request.Body()
 }
 
+func PeekBody(ctx *fasthttp.RequestCtx) []byte {
+	return append([]byte(nil), ctx.Request.Body()...)
+}
+
+func AbsoluteURL(ctx *fasthttp.RequestCtx, path string, trustedProxies []string) string {
+	scheme := "http"
+	if ctx.IsTLS() {
+		scheme = "https"
+	}
+	host := string(ctx.Host())
+	if ipTrusted(ctx.RemoteIP(), trustedProxies) {
+		if proto := firstHeaderValue(ctx, fasthttp.HeaderXForwardedProto); proto != "" {
+			scheme = strings.ToLower(proto)
+		}
+		if forwarded := firstHeaderValue(ctx, "X-Forwarded-Host"); forwarded != "" {
+			host = forwarded
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
+
+func firstHeaderValue(ctx *fasthttp.RequestCtx, key string) string {
+	value := string(ctx.Request.Header.Peek(key))
+	if i := strings.IndexByte(value, ','); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+func readRequestBody(ctx *fasthttp.RequestCtx, limit int64, deadline time.Time) error {
+	if int64(ctx.Request.Header.ContentLength()) > limit {
+		return ErrBodyTooLarge
+	}
+	stream := ctx.RequestBodyStream()
+	if stream == nil {
+		if int64(len(ctx.Request.Body())) > limit {
+			return ErrBodyTooLarge
+		}
+		return nil
+	}
+	if !deadline.IsZero() {
+		stream = &deadlineReader{r: stream, deadline: deadline}
+	}
+	body, err := io.ReadAll(io.LimitReader(stream, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > limit {
+		return ErrBodyTooLarge
+	}
+	ctx.Request.SetBody(body)
+	return nil
+}
+
+type deadlineReader struct {
+	r        io.Reader
+	deadline time.Time
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	if time.Now().After(d.deadline) {
+		return 0, ErrBodyTimeout
+	}
+	return d.r.Read(p)
+}
+
+func MultipartForm(ctx *fasthttp.RequestCtx, maxBodySize int64) (*multipart.Form, error) {
+	if maxBodySize > 0 {
+		if err := readRequestBody(ctx, maxBodySize, time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+	return ctx.MultipartForm()
+}
+
+func DecodeJSONStream(ctx *fasthttp.RequestCtx, each func(dec *json.Decoder) error) error {
+	var body io.Reader
+	if stream := ctx.RequestBodyStream(); stream != nil {
+		body = stream
+	} else {
+		body = bytes.NewReader(ctx.Request.Body())
+	}
+	dec := json.NewDecoder(body)
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return ErrNotJSONArray
+	}
+	for dec.More() {
+		if err := each(dec); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
 func (r *Router) recv(ctx *fasthttp.RequestCtx) {
 	if rcv := recover(); rcv != nil {
-		r.PanicHandler(ctx, rcv)
+		if r.PanicHandler != nil {
+			r.PanicHandler(ctx, rcv)
+		} else {
+			log.Printf("ming: panic serving %s %s: %v", ctx.Method(), ctx.Path(), rcv)
+			r.writeError(ctx, "internal server error", fasthttp.StatusInternalServerError)
+		}
 	}
 }
@@ -0,0 +1,290 @@
+package ming
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestMultipartFormChunkedLimit(t *testing.T) {
+	ctx := newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.Header.SetContentType("multipart/form-data; boundary=b")
+	ctx.Request.SetBodyStream(strings.NewReader(strings.Repeat("x", 64)), -1)
+	if _, err := MultipartForm(ctx, 16); err != ErrBodyTooLarge {
+		t.Fatalf("err = %v, want ErrBodyTooLarge", err)
+	}
+}
+
+func TestAbsoluteURLTrustedProxies(t *testing.T) {
+	newRequest := func(peer string) *fasthttp.RequestCtx {
+		req := &fasthttp.Request{}
+		req.SetRequestURI("http://internal:8080/")
+		req.Header.Set(fasthttp.HeaderXForwardedProto, "https")
+		req.Header.Set("X-Forwarded-Host", "example.com")
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Init(req, &net.TCPAddr{IP: net.ParseIP(peer)}, nil)
+		return ctx
+	}
+	trusted := []string{"10.0.0.0/8"}
+	if got := AbsoluteURL(newRequest("10.1.2.3"), "/x", trusted); got != "https://example.com/x" {
+		t.Fatalf("trusted peer url = %q", got)
+	}
+	if got := AbsoluteURL(newRequest("203.0.113.9"), "/x", trusted); got != "http://internal:8080/x" {
+		t.Fatalf("untrusted peer url = %q", got)
+	}
+}
+
+func TestCloneMaintenanceAllowIsIndependent(t *testing.T) {
+	r := New()
+	r.MaintenanceAllow = make([]string, 1, 4)
+	r.MaintenanceAllow[0] = "/health"
+	clone := r.Clone()
+	clone.MaintenanceAllow[0] = "/changed"
+	clone.MaintenanceAllow = append(clone.MaintenanceAllow, "/extra")
+	if r.MaintenanceAllow[0] != "/health" || len(r.MaintenanceAllow) != 1 {
+		t.Fatalf("original MaintenanceAllow changed: %v", r.MaintenanceAllow)
+	}
+}
+
+var _ fasthttp.RequestHandler = (*Router)(nil).Handler
+
+func TestFastHTTPHandlerThroughServer(t *testing.T) {
+	r := New()
+	r.Get("/", ok)
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	server := &fasthttp.Server{Handler: r.FastHTTPHandler()}
+	go server.Serve(ln)
+
+	client := &fasthttp.Client{Dial: func(addr string) (net.Conn, error) { return ln.Dial() }}
+	status, body, err := client.Get(nil, "http://test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != fasthttp.StatusOK || string(body) != "ok" {
+		t.Fatalf("response = %d %q", status, body)
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	r := New()
+	r.Get("/", ok)
+	clone := r.Clone()
+	clone.Get("/extra", ok)
+	if resp := clone.ServeTest(fasthttp.MethodGet, "/extra", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("clone /extra status = %d, want 200", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/extra", nil); resp.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("original /extra status = %d, want 404", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("original / status = %d, want 200", resp.StatusCode())
+	}
+}
+
+func TestCloneRebindsHandlerE(t *testing.T) {
+	fail := func(ctx *fasthttp.RequestCtx) error { return ErrInvalidToken }
+	r := New()
+	r.GetE("/root", fail)
+	api := r.Group("/api")
+	api.GetE("/group", fail)
+
+	clone := r.Clone()
+	clone.ErrorHandler = func(ctx *fasthttp.RequestCtx, err error) {
+		ctx.SetStatusCode(fasthttp.StatusTeapot)
+	}
+	for _, path := range []string{"/root", "/api/group"} {
+		if resp := clone.ServeTest(fasthttp.MethodGet, path, nil); resp.StatusCode() != fasthttp.StatusTeapot {
+			t.Fatalf("clone %s status = %d, want 418", path, resp.StatusCode())
+		}
+		if resp := r.ServeTest(fasthttp.MethodGet, path, nil); resp.StatusCode() != fasthttp.StatusInternalServerError {
+			t.Fatalf("original %s status = %d, want 500", path, resp.StatusCode())
+		}
+	}
+
+	api.ErrorHandler = func(ctx *fasthttp.RequestCtx, err error) {
+		ctx.SetStatusCode(fasthttp.StatusConflict)
+	}
+	if resp := clone.ServeTest(fasthttp.MethodGet, "/api/group", nil); resp.StatusCode() != fasthttp.StatusTeapot {
+		t.Fatalf("clone group status after original change = %d, want 418", resp.StatusCode())
+	}
+}
+
+func TestMultipartForm(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("name", "ming")
+	part, _ := w.CreateFormFile("upload", "hello.txt")
+	part.Write([]byte("hello file"))
+	w.Close()
+
+	r := New()
+	r.Post("/", func(ctx *fasthttp.RequestCtx) {
+		form, err := MultipartForm(ctx, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := form.File["upload"][0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		ctx.WriteString(form.Value["name"][0] + " " + string(data))
+	})
+	ctx := newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.Header.SetContentType(w.FormDataContentType())
+	ctx.Request.SetBody(body.Bytes())
+	r.Handler(ctx)
+	if got := string(ctx.Response.Body()); got != "ming hello file" {
+		t.Fatalf("body = %q", got)
+	}
+
+	ctx = newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.Header.SetContentType(w.FormDataContentType())
+	ctx.Request.SetBody(body.Bytes())
+	if _, err := MultipartForm(ctx, 16); err != ErrBodyTooLarge {
+		t.Fatalf("err = %v, want ErrBodyTooLarge", err)
+	}
+}
+
+func TestSetMaintenance(t *testing.T) {
+	r := New()
+	r.MaintenanceAllow = []string{"/health"}
+	r.Get("/", ok)
+	r.Get("/health", ok)
+
+	r.SetMaintenance(true, 120)
+	resp := r.ServeTest(fasthttp.MethodGet, "/", nil)
+	if resp.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("maintenance status = %d, want 503", resp.StatusCode())
+	}
+	if got := string(resp.Header.Peek(fasthttp.HeaderRetryAfter)); got != "120" {
+		t.Fatalf("Retry-After = %q, want 120", got)
+	}
+	if resp := r.ServeTest(fasthttp.MethodGet, "/health", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("allowlisted path status = %d, want 200", resp.StatusCode())
+	}
+
+	r.SetMaintenance(false, 0)
+	if resp := r.ServeTest(fasthttp.MethodGet, "/", nil); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("after maintenance status = %d, want 200", resp.StatusCode())
+	}
+}
+
+func TestNewServerAppliesOptions(t *testing.T) {
+	server := newServer(
+		WithConcurrency(64),
+		WithDisableKeepalive(),
+		WithReadTimeout(time.Second),
+		WithWriteTimeout(2*time.Second),
+		WithIdleTimeout(3*time.Second),
+		WithMaxRequestBodySize(1<<10),
+		WithNoDefaultServerHeader(),
+	)
+	if server.Concurrency != 64 || !server.DisableKeepalive || !server.NoDefaultServerHeader {
+		t.Fatalf("server = %+v", server)
+	}
+	if server.ReadTimeout != time.Second || server.WriteTimeout != 2*time.Second || server.IdleTimeout != 3*time.Second {
+		t.Fatalf("timeouts = %v %v %v", server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+	}
+	if server.MaxRequestBodySize != 1<<10 {
+		t.Fatalf("MaxRequestBodySize = %d", server.MaxRequestBodySize)
+	}
+	if server := newServer(); server.Concurrency != 0 || server.DisableKeepalive {
+		t.Fatalf("server without options = %+v", server)
+	}
+}
+
+func TestPeekBody(t *testing.T) {
+	ctx := newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.SetBodyStream(strings.NewReader(`{"name":"alice"}`), -1)
+	first := PeekBody(ctx)
+	first[0] = 'X'
+	second := PeekBody(ctx)
+	if string(second) != `{"name":"alice"}` {
+		t.Fatalf("second read = %q, want the original body", second)
+	}
+	if string(Body(ctx)) != `{"name":"alice"}` {
+		t.Fatalf("Body after PeekBody = %q", Body(ctx))
+	}
+}
+
+func TestRequestCounters(t *testing.T) {
+	r := New()
+	r.Get("/", ok)
+	r.Get("/fail", func(ctx *fasthttp.RequestCtx) { ctx.SetStatusCode(fasthttp.StatusInternalServerError) })
+
+	r.ServeTest(fasthttp.MethodGet, "/", nil)
+	r.ServeTest(fasthttp.MethodGet, "/", nil)
+	r.ServeTest(fasthttp.MethodGet, "/missing", nil)
+	r.ServeTest(fasthttp.MethodGet, "/fail", nil)
+
+	if got := r.RequestCount(); got != 4 {
+		t.Fatalf("RequestCount = %d, want 4", got)
+	}
+	for class, want := range map[int]uint64{2: 2, 3: 0, 4: 1, 5: 1, 0: 0, 6: 0} {
+		if got := r.StatusClassCount(class); got != want {
+			t.Errorf("StatusClassCount(%d) = %d, want %d", class, got, want)
+		}
+	}
+}
+
+func TestDecodeJSONStream(t *testing.T) {
+	decode := func(ctx *fasthttp.RequestCtx) ([]int, error) {
+		var ids []int
+		err := DecodeJSONStream(ctx, func(dec *json.Decoder) error {
+			var item struct{ ID int }
+			if err := dec.Decode(&item); err != nil {
+				return err
+			}
+			ids = append(ids, item.ID)
+			return nil
+		})
+		return ids, err
+	}
+	const body = `[{"id":1},{"id":2},{"id":3}]`
+
+	ctx := newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.SetBodyString(body)
+	if ids, err := decode(ctx); err != nil || fmt.Sprint(ids) != "[1 2 3]" {
+		t.Fatalf("buffered body: %v, %v", ids, err)
+	}
+	ctx = newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.SetBodyStream(strings.NewReader(body), -1)
+	if ids, err := decode(ctx); err != nil || fmt.Sprint(ids) != "[1 2 3]" {
+		t.Fatalf("streamed body: %v, %v", ids, err)
+	}
+	ctx = newCtx(fasthttp.MethodPost, "/")
+	ctx.Request.SetBodyString(`{"id":1}`)
+	if _, err := decode(ctx); err != ErrNotJSONArray {
+		t.Fatalf("object body err = %v, want ErrNotJSONArray", err)
+	}
+}
+
+func TestSetHeadersAndAddHeader(t *testing.T) {
+	ctx := newCtx(fasthttp.MethodGet, "/")
+	SetHeaders(ctx, map[string]string{"X-One": "1", "X-Two": "2"})
+	AddHeader(ctx, "X-Tag", "a")
+	AddHeader(ctx, "X-Tag", "b")
+
+	if one, two := string(ctx.Response.Header.Peek("X-One")), string(ctx.Response.Header.Peek("X-Two")); one != "1" || two != "2" {
+		t.Fatalf("X-One = %q, X-Two = %q", one, two)
+	}
+	var tags []string
+	for _, v := range ctx.Response.Header.PeekAll("X-Tag") {
+		tags = append(tags, string(v))
+	}
+	if fmt.Sprint(tags) != "[a b]" {
+		t.Fatalf("X-Tag values = %v, want [a b]", tags)
+	}
+}
@@ -6,9 +6,13 @@ import (
 
 type Tree []*Node
 type Node struct {
-	method  string
-	path    string
-	handler fasthttp.RequestHandler
+	method   string
+	path     string
+	handler  fasthttp.RequestHandler
+	handlerE HandlerE
+	group    *RouteGroup
+	doc      *RouteDoc
+	query    map[string]string
 }
 
 func (t *Tree) Add(n *Node) {
@@ -53,3 +57,79 @@ func (t *Tree) GetMethodAll() *Node {
 		return nil
 	}
 }
+
+func (t *Tree) Remove(method, path string) bool {
+	kept := (*t)[:0]
+	for _, v := range *t {
+		if v.method != method || v.path != path {
+			kept = append(kept, v)
+		}
+	}
+	removed := len(kept) != len(*t)
+	for i := len(kept); i < len(*t); i++ {
+		(*t)[i] = nil
+	}
+	*t = kept
+	return removed
+}
+
+func (t *Tree) Walk(fn func(path string, handlers map[string]fasthttp.RequestHandler)) {
+	paths := []string{}
+	handlers := map[string]map[string]fasthttp.RequestHandler{}
+	for _, v := range *t {
+		if _, ok := handlers[v.path]; !ok {
+			paths = append(paths, v.path)
+			handlers[v.path] = map[string]fasthttp.RequestHandler{}
+		}
+		if _, ok := handlers[v.path][v.method]; !ok {
+			handlers[v.path][v.method] = v.handler
+		}
+	}
+	for _, path := range paths {
+		fn(path, handlers[path])
+	}
+}
+
+func (t *Tree) Clone() *Tree {
+	result := make(Tree, 0, len(*t))
+	for _, v := range *t {
+		n := *v
+		result = append(result, &n)
+	}
+	return &result
+}
+
+func (t *Tree) Methods() []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, v := range *t {
+		if !seen[v.method] {
+			seen[v.method] = true
+			result = append(result, v.method)
+		}
+	}
+	return result
+}
+
+func (t *Tree) MatchQuery(args *fasthttp.Args) *Tree {
+	result := &Tree{}
+	rest := Tree{}
+	for _, v := range *t {
+		if v.query == nil {
+			rest = append(rest, v)
+		} else if v.matchQuery(args) {
+			result.Add(v)
+		}
+	}
+	*result = append(*result, rest...)
+	return result
+}
+
+func (n *Node) matchQuery(args *fasthttp.Args) bool {
+	for key, value := range n.query {
+		if string(args.Peek(key)) != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package ming
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestTreeRemove(t *testing.T) {
+	tree := &Tree{}
+	tree.Add(&Node{method: "GET", path: "/a", query: map[string]string{"v": "1"}})
+	tree.Add(&Node{method: "GET", path: "/a"})
+	tree.Add(&Node{method: "POST", path: "/a"})
+	tree.Add(&Node{method: "GET", path: "/b"})
+
+	if !tree.Remove("GET", "/a") {
+		t.Fatal("Remove returned false for an existing route")
+	}
+	if tree.Len() != 2 || tree.FindPath("/a").FindMethod("GET") != nil {
+		t.Fatalf("GET /a still present after Remove: %d nodes", tree.Len())
+	}
+	if tree.FindPath("/a").FindMethod("POST") == nil || tree.FindPath("/b").Len() != 1 {
+		t.Fatal("sibling routes removed")
+	}
+	if tree.Remove("GET", "/a") {
+		t.Fatal("Remove returned true for a missing route")
+	}
+}
+
+func TestTreeWalk(t *testing.T) {
+	tree := &Tree{}
+	tree.Add(&Node{method: "GET", path: "/a"})
+	tree.Add(&Node{method: "POST", path: "/a"})
+	tree.Add(&Node{method: "GET", path: "/b"})
+
+	var paths []string
+	methods := map[string]int{}
+	tree.Walk(func(path string, handlers map[string]fasthttp.RequestHandler) {
+		paths = append(paths, path)
+		methods[path] = len(handlers)
+	})
+	if len(paths) != 2 || paths[0] != "/a" || paths[1] != "/b" {
+		t.Fatalf("walked paths = %v, want [/a /b]", paths)
+	}
+	if methods["/a"] != 2 || methods["/b"] != 1 {
+		t.Fatalf("methods per path = %v", methods)
+	}
+}
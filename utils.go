@@ -1,6 +1,11 @@
 package ming
 
-import "github.com/valyala/fasthttp"
+import (
+	"net"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
 
 func GetMethod(ctx *fasthttp.RequestCtx) string {
 	switch true {
@@ -9,7 +14,7 @@ func GetMethod(ctx *fasthttp.RequestCtx) string {
 	case ctx.IsPost():
 		return fasthttp.MethodPost
 	case ctx.IsHead():
-		return fasthttp.MethodPatch
+		return fasthttp.MethodHead
 	case ctx.IsPut():
 		return fasthttp.MethodPut
 	case ctx.IsPatch():
@@ -26,3 +31,60 @@ func GetMethod(ctx *fasthttp.RequestCtx) string {
 		return ""
 	}
 }
+
+func requestMethod(ctx *fasthttp.RequestCtx) string {
+	if method := GetMethod(ctx); method != "" {
+		return method
+	}
+	return string(ctx.Method())
+}
+
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+func writeError(ctx *fasthttp.RequestCtx, msg string, statusCode int) {
+	ctx.SetUserValue(errorKey, statusCode)
+	ctx.SetStatusCode(statusCode)
+	ctx.SetContentTypeBytes(DefaultContentType)
+	ctx.SetBodyString(msg)
+}
+
+func ClientIP(ctx *fasthttp.RequestCtx, trustedProxies []string) string {
+	peer := ctx.RemoteIP()
+	if !ipTrusted(peer, trustedProxies) {
+		return peer.String()
+	}
+	if forwarded := string(ctx.Request.Header.Peek(fasthttp.HeaderXForwardedFor)); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			if i == 0 || !ipTrusted(ip, trustedProxies) {
+				return ip.String()
+			}
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(string(ctx.Request.Header.Peek("X-Real-IP")))); ip != nil {
+		return ip.String()
+	}
+	return peer.String()
+}
+
+func ipTrusted(ip net.IP, trustedProxies []string) bool {
+	for _, proxy := range trustedProxies {
+		if strings.Contains(proxy, "/") {
+			if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if trusted := net.ParseIP(proxy); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
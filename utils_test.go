@@ -0,0 +1,37 @@
+package ming
+
+import (
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.168.1.1"}
+	cases := []struct {
+		name, peer, forwarded, realIP, want string
+	}{
+		{"trusted proxy forwards client", "10.0.0.5", "203.0.113.7", "", "203.0.113.7"},
+		{"chain of trusted proxies", "10.0.0.5", "203.0.113.7, 10.1.1.1", "", "203.0.113.7"},
+		{"spoofed leading hop", "10.0.0.5", "1.1.1.1, 203.0.113.7", "", "203.0.113.7"},
+		{"trusted single ip", "192.168.1.1", "203.0.113.7", "", "203.0.113.7"},
+		{"X-Real-IP fallback", "10.0.0.5", "", "203.0.113.8", "203.0.113.8"},
+		{"untrusted peer ignored", "198.51.100.2", "203.0.113.7", "203.0.113.8", "198.51.100.2"},
+		{"malformed header", "10.0.0.5", "not-an-ip", "", "10.0.0.5"},
+	}
+	for _, c := range cases {
+		req := &fasthttp.Request{}
+		if c.forwarded != "" {
+			req.Header.Set(fasthttp.HeaderXForwardedFor, c.forwarded)
+		}
+		if c.realIP != "" {
+			req.Header.Set("X-Real-IP", c.realIP)
+		}
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Init(req, &net.TCPAddr{IP: net.ParseIP(c.peer)}, nil)
+		if got := ClientIP(ctx, trusted); got != c.want {
+			t.Errorf("%s: ClientIP = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ming
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type fieldRule struct {
+	name string
+	arg  string
+}
+
+func parseRules(rules string) []fieldRule {
+	var parsed []fieldRule
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), ":")
+		if name = strings.TrimSpace(name); name != "" {
+			parsed = append(parsed, fieldRule{name: name, arg: strings.TrimSpace(arg)})
+		}
+	}
+	return parsed
+}
+
+func ValidateJSON(rules map[string]string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	parsed := make(map[string][]fieldRule, len(rules))
+	for field, rule := range rules {
+		parsed[field] = parseRules(rule)
+	}
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			var data map[string]interface{}
+			if err := json.Unmarshal(ctx.Request.Body(), &data); err != nil {
+				writeError(ctx, "invalid json body", fasthttp.StatusBadRequest)
+				return
+			}
+			errs := map[string]string{}
+			for field, fieldRules := range parsed {
+				if msg := checkRules(data, field, fieldRules); msg != "" {
+					errs[field] = msg
+				}
+			}
+			if len(errs) != 0 {
+				body, _ := json.Marshal(map[string]interface{}{"errors": errs})
+				ctx.SetStatusCode(fasthttp.StatusUnprocessableEntity)
+				ctx.SetContentType("application/json")
+				ctx.SetBody(body)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+func checkRules(data map[string]interface{}, field string, rules []fieldRule) string {
+	value, ok := data[field]
+	if !ok || value == nil {
+		for _, rule := range rules {
+			if rule.name == "required" {
+				return "is required"
+			}
+		}
+		return ""
+	}
+	for _, rule := range rules {
+		name, arg := rule.name, rule.arg
+		switch name {
+		case "required":
+		case "email":
+			s, _ := value.(string)
+			if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+				return "must be a valid email"
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return fmt.Sprintf("invalid rule %q", name+":"+arg)
+			}
+			var size float64
+			switch v := value.(type) {
+			case string:
+				size = float64(len([]rune(v)))
+			case float64:
+				size = v
+			case []interface{}:
+				size = float64(len(v))
+			}
+			if name == "min" && size < limit {
+				return fmt.Sprintf("must be at least %s", arg)
+			}
+			if name == "max" && size > limit {
+				return fmt.Sprintf("must be at most %s", arg)
+			}
+		default:
+			return fmt.Sprintf("unknown rule %q", name)
+		}
+	}
+	return ""
+}
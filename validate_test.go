@@ -0,0 +1,22 @@
+package ming
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestValidateJSONRequiredWithSpaces(t *testing.T) {
+	r := New()
+	r.Post("/", ValidateJSON(map[string]string{"email": "email, required", "name": "min: 2"})(ok))
+
+	if resp := r.ServeTest(fasthttp.MethodPost, "/", []byte(`{"name":"ab"}`)); resp.StatusCode() != fasthttp.StatusUnprocessableEntity {
+		t.Fatalf("missing required field status = %d, want 422", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/", []byte(`{"email":"a@b.co","name":"a"}`)); resp.StatusCode() != fasthttp.StatusUnprocessableEntity {
+		t.Fatalf("short name status = %d, want 422", resp.StatusCode())
+	}
+	if resp := r.ServeTest(fasthttp.MethodPost, "/", []byte(`{"email":"a@b.co","name":"ab"}`)); resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("valid body status = %d, want 200", resp.StatusCode())
+	}
+}